@@ -1,92 +1,241 @@
-package main
-
-import (
-	"embed"
-	"flag"
-	"fmt"
-	"io/fs"
-	"log"
-	"net/http"
-	"spotlive-server/internal/config"
-	"spotlive-server/internal/server"
-
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
-)
-
-//go:embed webapp/dist
-var staticFiles embed.FS
-
-func main() {
-	// Flags
-	port := flag.String("port", "8080", "Server port")
-	dataDir := flag.String("data", "/data/data/com.spotlive.player/files", "Data directory")
-	debug := flag.Bool("debug", false, "Debug mode")
-	flag.Parse()
-
-	// Imposta data directory
-	config.SetDataDir(*dataDir)
-
-	// Carica configurazione
-	if _, err := config.Load(); err != nil {
-		log.Printf("Warning: failed to load config: %v (using defaults)", err)
-	}
-
-	// Gin mode
-	if !*debug {
-		gin.SetMode(gin.ReleaseMode)
-	}
-
-	// Router
-	r := gin.Default()
-
-	// CORS (permetti richieste da WebView)
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-	}))
-
-	// API routes
-	api := r.Group("/api")
-	{
-		// Config
-		api.GET("/config", server.GetConfig)
-		api.POST("/config", server.SaveConfig)
-		api.POST("/config/test", server.TestConnection)
-
-		// Schedule
-		api.GET("/schedule", server.GetSchedule)
-
-		// Media
-		api.GET("/media/:filename", server.DownloadMedia)
-		api.GET("/cache/:filename", server.GetCachedMedia)
-		api.POST("/cache/:filename", server.CacheMedia)
-		api.POST("/media/download-all", server.DownloadAllMedia)
-
-		// Status
-		api.GET("/status", server.GetStatus)
-		api.POST("/heartbeat", server.SendHeartbeat)
-	}
-
-	// Serve PWA statica
-	staticFS, err := fs.Sub(staticFiles, "webapp/dist")
-	if err != nil {
-		log.Fatal(err)
-	}
-	r.NoRoute(func(c *gin.Context) {
-		c.FileFromFS(c.Request.URL.Path, http.FS(staticFS))
-	})
-
-	// Start server
-	addr := fmt.Sprintf(":%s", *port)
-	log.Printf("SpotLiveScreen Server v6.0")
-	log.Printf("Listening on http://localhost%s", addr)
-	log.Printf("Data directory: %s", *dataDir)
-
-	if err := r.Run(addr); err != nil {
-		log.Fatal(err)
-	}
-}
+package main
+
+import (
+	"embed"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"spotlive-server/internal/auth"
+	"spotlive-server/internal/config"
+	"spotlive-server/internal/ftp"
+	"spotlive-server/internal/media"
+	"spotlive-server/internal/schedule"
+	"spotlive-server/internal/server"
+	"spotlive-server/internal/xml"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed webapp/dist
+var staticFiles embed.FS
+
+func main() {
+	// Flags
+	port := flag.String("port", "8080", "Server port")
+	bind := flag.String("bind", "127.0.0.1", "Bind address (0.0.0.0 to expose on LAN)")
+	dataDir := flag.String("data", "/data/data/com.spotlive.player/files", "Data directory")
+	adminToken := flag.String("admin-token", os.Getenv("SPOTLIVE_ADMIN_TOKEN"), "Admin token for mutating endpoints from LAN (default: generated in data dir)")
+	configKey := flag.String("config-key", os.Getenv("SPOTLIVE_CONFIG_KEY"), "Device secret for config encryption (e.g. from Android keystore; default: generated in data dir)")
+	corsOrigins := flag.String("cors-origins", "", "Extra allowed CORS origins (comma separated)")
+	debug := flag.Bool("debug", false, "Debug mode")
+	provision := flag.String("provision", "", "Provisioning bundle applied on first boot (when not yet configured)")
+	provisionKey := flag.String("provision-key", os.Getenv("SPOTLIVE_PROVISION_KEY"), "Passphrase of the provisioning bundle")
+	serial := flag.String("serial", os.Getenv("SPOTLIVE_DEVICE_SERIAL"), "Device serial (selects monitor ID from provisioning bundle)")
+	overrides := setFlags{}
+	flag.Var(overrides, "set", "Override config field, e.g. -set serverUrl=http://host:80 (repeatable, wins over SPOTLIVE_* env)")
+	flag.Parse()
+
+	// Imposta data directory e segreto per cifratura config
+	config.SetDataDir(*dataDir)
+	config.SetKeyMaterial(*configKey)
+	config.SetDeviceSerial(*serial)
+
+	// Overlay da variabili SPOTLIVE_* e flag -set (mai scritti nel file)
+	if err := config.LoadOverlayFromEnv(overrides); err != nil {
+		log.Fatalf("Invalid config override: %v", err)
+	}
+
+	// Token admin (richiesto per le modifiche da client non loopback)
+	if err := auth.Init(*dataDir, *adminToken); err != nil {
+		log.Fatalf("Admin token init failed: %v", err)
+	}
+
+	// Carica configurazione
+	if _, err := config.Load(); err != nil {
+		log.Printf("Warning: failed to load config: %v (using defaults)", err)
+	}
+
+	// Primo avvio: applica bundle di provisioning
+	if *provision != "" && !config.IsConfigured() {
+		if _, err := config.ApplyProvisionFile(*provision, *provisionKey); err != nil {
+			log.Printf("Warning: provisioning failed: %v", err)
+		} else {
+			log.Printf("Provisioning bundle %s applied", *provision)
+		}
+	}
+
+	// Senza credenziali FTP la sincronizzazione media resta disabilitata
+	if err := ftp.CheckConfigured(config.Get()); err != nil {
+		log.Printf("Warning: %v: media sync disabled until FTP credentials are provisioned or received from the server", err)
+	}
+
+	// Apre store media (migra una sola volta il layout flat preesistente)
+	if store, err := media.For(config.Get().MediaDir); err != nil {
+		log.Printf("Warning: media store unavailable: %v", err)
+	} else if err := store.MigrateFlat(); err != nil {
+		log.Printf("Warning: media migration failed: %v", err)
+	}
+
+	// Ricarica config su SIGHUP o modifica del file
+	go watchConfig()
+
+	// Storico programmazione: versioni recenti e differenze nei log
+	go schedule.Track()
+
+	// Gin mode
+	if !*debug {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	// Router
+	r := gin.Default()
+
+	// Nessun proxy fidato: ClientIP/RemoteIP non devono dipendere da X-Forwarded-For
+	if err := r.SetTrustedProxies(nil); err != nil {
+		log.Fatal(err)
+	}
+
+	// CORS: solo origini locali (WebView) più quelle configurate
+	origins := []string{
+		fmt.Sprintf("http://localhost:%s", *port),
+		fmt.Sprintf("http://127.0.0.1:%s", *port),
+	}
+	for _, o := range strings.Split(*corsOrigins, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			origins = append(origins, o)
+		}
+	}
+	r.Use(cors.New(cors.Config{
+		AllowOrigins:     origins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", server.AdminTokenHeader},
+		ExposeHeaders:    []string{"Content-Length"},
+		AllowCredentials: false,
+	}))
+
+	// API routes
+	api := r.Group("/api")
+	admin := api.Group("", server.RequireAdmin())
+	{
+		// Config
+		api.GET("/config", server.GetConfig)
+		admin.GET("/config/effective", server.GetEffectiveConfig)
+		admin.POST("/config/export", server.ExportConfig)
+		admin.POST("/config/import", server.ImportConfig)
+		api.POST("/admin/reset", server.RequireAdminToken(), server.ResetDevice)
+		admin.POST("/config", server.SaveConfig)
+		admin.PATCH("/config", server.PatchConfig)
+		admin.POST("/config/test", server.TestConnection)
+
+		// Schedule
+		api.GET("/schedule", server.GetSchedule)
+		api.GET("/v2/schedule", server.GetScheduleV2)
+		api.GET("/schedule/history", server.GetScheduleHistory)
+		api.GET("/schedule/diff", server.GetScheduleDiff)
+		api.GET("/schedule/lint", server.GetScheduleLint)
+
+		// Media
+		api.GET("/media/*filename", server.DownloadMedia)
+		api.GET("/cache/:filename", server.GetCachedMedia)
+		admin.POST("/cache/:filename", server.CacheMedia)
+		admin.POST("/media/download-all", server.DownloadAllMedia)
+
+		// Remote (FTP)
+		admin.GET("/remote/ls", server.ListRemote)
+
+		// Status
+		api.GET("/status", server.GetStatus)
+		admin.POST("/heartbeat", server.SendHeartbeat)
+	}
+
+	// Serve PWA statica
+	staticFS, err := fs.Sub(staticFiles, "webapp/dist")
+	if err != nil {
+		log.Fatal(err)
+	}
+	r.NoRoute(func(c *gin.Context) {
+		c.FileFromFS(c.Request.URL.Path, http.FS(staticFS))
+	})
+
+	// Start server
+	addr := net.JoinHostPort(*bind, *port)
+	log.Printf("SpotLiveScreen Server v6.0")
+	log.Printf("Listening on http://%s", addr)
+	log.Printf("Data directory: %s", *dataDir)
+	if *adminToken == "" {
+		log.Printf("Admin token file: %s", auth.TokenPath(*dataDir))
+	}
+
+	if err := r.Run(addr); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// setFlags valori del flag -set (campo JSON → valore)
+type setFlags map[string]string
+
+func (s setFlags) String() string {
+	pairs := make([]string, 0, len(s))
+	for k, v := range s {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (s setFlags) Set(value string) error {
+	field, v, ok := strings.Cut(value, "=")
+	if !ok || strings.TrimSpace(field) == "" {
+		return fmt.Errorf("expected field=value, got %q", value)
+	}
+	s[strings.TrimSpace(field)] = v
+	return nil
+}
+
+// watchConfig ricarica la config su SIGHUP o modifica del file e applica
+// le nuove impostazioni ai componenti che mantengono stato
+func watchConfig() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			log.Printf("SIGHUP: reloading config")
+			if err := config.Reload(); err != nil {
+				log.Printf("Warning: config reload failed: %v", err)
+			}
+		}
+	}()
+
+	go config.WatchFile(5*time.Second, nil)
+
+	changes, _ := config.Subscribe()
+	prev := config.Get()
+	for cfg := range changes {
+		log.Printf("Config updated (monitor %s, server %s)", cfg.IDMonitor, cfg.ServerURL)
+
+		// Schermo o server diversi: la programmazione precedente non vale più
+		if cfg.ServerURL != prev.ServerURL || cfg.IDMonitor != prev.IDMonitor || cfg.UserSchermo != prev.UserSchermo {
+			xml.ResetLastSchedule()
+			schedule.ResetHistory()
+		}
+		// Servlet o protocollo diversi: capacità da negoziare di nuovo
+		if cfg.ServerURL != prev.ServerURL || cfg.ServletPath != prev.ServletPath ||
+			cfg.ProtocolVersion != prev.ProtocolVersion || cfg.AuthMode != prev.AuthMode {
+			xml.ResetCapabilities()
+		}
+		if cfg.MediaDir != prev.MediaDir {
+			if _, err := media.For(cfg.MediaDir); err != nil {
+				log.Printf("Warning: media store unavailable: %v", err)
+			}
+		}
+		prev = cfg
+	}
+}
@@ -0,0 +1,80 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Code codice errore stabile esposto dalle API (usato dalla web UI)
+type Code string
+
+// Codici generici (non legati a un package specifico)
+const (
+	CodeInternal       Code = "INTERNAL_ERROR"
+	CodeInvalidRequest Code = "INVALID_REQUEST"
+	CodeNotFound       Code = "NOT_FOUND"
+)
+
+// Error errore tipizzato con codice, dettagli e indicazione di retry
+type Error struct {
+	Code      Code
+	Message   string
+	Details   map[string]interface{}
+	Retryable bool
+	Err       error
+}
+
+// New crea nuovo errore tipizzato
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap crea errore tipizzato che incapsula un errore sottostante
+func Wrap(err error, code Code, message string) *Error {
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+// Error implementa interfaccia error
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+// Unwrap ritorna errore sottostante
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithDetail aggiunge un dettaglio (es. campo, path, status HTTP)
+func (e *Error) WithDetail(key string, value interface{}) *Error {
+	if e.Details == nil {
+		e.Details = make(map[string]interface{})
+	}
+	e.Details[key] = value
+	return e
+}
+
+// AsRetryable marca errore come temporaneo (ritentare ha senso)
+func (e *Error) AsRetryable() *Error {
+	e.Retryable = true
+	return e
+}
+
+// From estrae errore tipizzato dalla catena, nil se assente
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return nil
+}
+
+// CodeOf ritorna codice errore (CodeInternal se errore non tipizzato)
+func CodeOf(err error) Code {
+	if e := From(err); e != nil {
+		return e.Code
+	}
+	return CodeInternal
+}
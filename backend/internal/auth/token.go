@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// tokenFileName file con token admin generato (permessi 0600)
+const tokenFileName = "admin.token"
+
+var (
+	mu         sync.RWMutex
+	adminToken string
+)
+
+// Init imposta token admin: usa quello fornito (flag/env) oppure carica o
+// genera quello salvato nella data directory
+func Init(dataDir, token string) error {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		var err error
+		token, err = loadOrCreate(filepath.Join(dataDir, tokenFileName))
+		if err != nil {
+			return err
+		}
+	}
+
+	mu.Lock()
+	adminToken = token
+	mu.Unlock()
+	return nil
+}
+
+// TokenPath ritorna path del file token nella data directory
+func TokenPath(dataDir string) string {
+	return filepath.Join(dataDir, tokenFileName)
+}
+
+// Check verifica token admin (confronto a tempo costante)
+func Check(token string) bool {
+	mu.RLock()
+	expected := adminToken
+	mu.RUnlock()
+
+	if expected == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// loadOrCreate legge token da file o ne genera uno nuovo
+func loadOrCreate(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("cannot write admin token: %w", err)
+	}
+	return token, nil
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic scrive su file temporaneo nella stessa directory, fsync,
+// poi rename sul file finale: dopo un'interruzione di corrente il file è
+// o la versione precedente o quella nuova, mai una scrittura parziale.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // no-op dopo rename riuscito
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir rende persistente la voce di directory dopo rename
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	// Alcuni filesystem non supportano fsync su directory: non è un errore
+	d.Sync()
+	return nil
+}
@@ -1,191 +1,365 @@
-package config
-
-import (
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
-	"encoding/base64"
-	"encoding/json"
-	"errors"
-	"io"
-	"os"
-	"path/filepath"
-)
-
-// Config struttura configurazione applicazione
-type Config struct {
-	// Server HTTP
-	ServerURL    string `json:"serverUrl"`    // http://80.88.90.214:80
-
-	// Autenticazione
-	Username     string `json:"username"`     // 01fcbgyvir
-	Password     string `json:"password"`     // 01fcbgyvir (encrypted)
-	IDMonitor    string `json:"idMonitor"`    // 567
-	UserSchermo  string `json:"userSchermo"`  // cucciniello
-
-	// FTP
-	FTPServer    string `json:"ftpServer"`    // 80.88.90.214
-	FTPPort      int    `json:"ftpPort"`      // 21
-	FTPUsername  string `json:"ftpUsername"`  // ftptomcat
-	FTPPassword  string `json:"ftpPassword"`  // LiveScreenSRL_2022 (encrypted)
-	FTPDirectory string `json:"ftpDirectory"` // /
-
-	// Configurazione player
-	ConnectionMode   int    `json:"connectionMode"`   // 3 = PASV
-	VideoQuality     int    `json:"videoQuality"`     // 34
-	Delay            int    `json:"delay"`            // 20000 ms
-	SecondiCache     int    `json:"secondiCache"`     // 5
-	SecondiTolleranza int   `json:"secondiTolleranza"` // 12
-
-	// Paths
-	DataDir      string `json:"dataDir"`      // /data/data/com.spotlive.player/files
-	MediaDir     string `json:"mediaDir"`     // dataDir/media
-	CacheDir     string `json:"cacheDir"`     // dataDir/cache
-}
-
-var (
-	currentConfig *Config
-	configPath    string
-	encryptionKey = []byte("spotlive2024key!") // 16 bytes for AES-128
-)
-
-// SetDataDir imposta directory dati (chiamato da Android)
-func SetDataDir(dir string) {
-	configPath = filepath.Join(dir, "config.json")
-}
-
-// Load carica configurazione da file
-func Load() (*Config, error) {
-	if configPath == "" {
-		return nil, errors.New("data directory not set")
-	}
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// Config non esiste, ritorna default
-			return GetDefault(), nil
-		}
-		return nil, err
-	}
-
-	// Decripta
-	decrypted, err := decrypt(data)
-	if err != nil {
-		return nil, err
-	}
-
-	var cfg Config
-	if err := json.Unmarshal(decrypted, &cfg); err != nil {
-		return nil, err
-	}
-
-	currentConfig = &cfg
-	return currentConfig, nil
-}
-
-// Save salva configurazione su file (encrypted)
-func Save(cfg *Config) error {
-	if configPath == "" {
-		return errors.New("data directory not set")
-	}
-
-	// Assicura che le directory esistano
-	cfg.MediaDir = filepath.Join(cfg.DataDir, "media")
-	cfg.CacheDir = filepath.Join(cfg.DataDir, "cache")
-	os.MkdirAll(cfg.MediaDir, 0755)
-	os.MkdirAll(cfg.CacheDir, 0755)
-
-	data, err := json.MarshalIndent(cfg, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	// Cripta
-	encrypted, err := encrypt(data)
-	if err != nil {
-		return err
-	}
-
-	// Salva
-	if err := os.WriteFile(configPath, encrypted, 0600); err != nil {
-		return err
-	}
-
-	currentConfig = cfg
-	return nil
-}
-
-// Get ritorna configurazione corrente
-func Get() *Config {
-	if currentConfig == nil {
-		currentConfig = GetDefault()
-	}
-	return currentConfig
-}
-
-// GetDefault ritorna configurazione di default
-func GetDefault() *Config {
-	return &Config{
-		ServerURL:         "http://80.88.90.214:80",
-		FTPServer:        "80.88.90.214",
-		FTPPort:          21,
-		FTPUsername:      "ftptomcat",
-		FTPPassword:      "LiveScreenSRL_2022",
-		FTPDirectory:     "/",
-		ConnectionMode:   3,
-		VideoQuality:     34,
-		Delay:            20000,
-		SecondiCache:     5,
-		SecondiTolleranza: 12,
-	}
-}
-
-// IsConfigured verifica se app è configurata
-func IsConfigured() bool {
-	cfg := Get()
-	return cfg.Username != "" && cfg.Password != "" && cfg.IDMonitor != ""
-}
-
-// Encrypt/Decrypt helpers
-func encrypt(plaintext []byte) ([]byte, error) {
-	block, err := aes.NewCipher(encryptionKey)
-	if err != nil {
-		return nil, err
-	}
-
-	ciphertext := make([]byte, aes.BlockSize+len(plaintext))
-	iv := ciphertext[:aes.BlockSize]
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
-		return nil, err
-	}
-
-	stream := cipher.NewCFBEncrypter(block, iv)
-	stream.XORKeyStream(ciphertext[aes.BlockSize:], plaintext)
-
-	return []byte(base64.StdEncoding.EncodeToString(ciphertext)), nil
-}
-
-func decrypt(ciphertext []byte) ([]byte, error) {
-	decoded, err := base64.StdEncoding.DecodeString(string(ciphertext))
-	if err != nil {
-		return nil, err
-	}
-
-	block, err := aes.NewCipher(encryptionKey)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(decoded) < aes.BlockSize {
-		return nil, errors.New("ciphertext too short")
-	}
-
-	iv := decoded[:aes.BlockSize]
-	decoded = decoded[aes.BlockSize:]
-
-	stream := cipher.NewCFBDecrypter(block, iv)
-	stream.XORKeyStream(decoded, decoded)
-
-	return decoded, nil
-}
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"spotlive-server/internal/apperr"
+)
+
+// Config struttura configurazione applicazione
+type Config struct {
+	// Versione schema del file persistito (vedi migrate.go)
+	SchemaVersion int `json:"schemaVersion"`
+
+	// Server HTTP
+	ServerURL    string `json:"serverUrl"`    // http://80.88.90.214:80
+
+	// Servlet programmazione
+	ServletPath     string `json:"servletPath"`     // /spotlivescreen/XmlServlet
+	ProtocolVersion int    `json:"protocolVersion"` // 600
+	AuthMode        string `json:"authMode"`        // auto, none, query, basic, token
+
+	// Client HTTP verso il servlet
+	HTTPConnectTimeout int    `json:"httpConnectTimeout"` // 10 s
+	HTTPReadTimeout    int    `json:"httpReadTimeout"`    // 30 s
+	HTTPProxy          string `json:"httpProxy"`          // vuoto = HTTP_PROXY/HTTPS_PROXY
+	CABundle           string `json:"caBundle"`           // PEM aggiuntivo ai CA di sistema
+	MaxScheduleBytes   int    `json:"maxScheduleBytes"`   // 8 MiB, risposta programmazione
+
+	// Autenticazione
+	Username     string `json:"username"`
+	Password     string `json:"password"`     // (encrypted)
+	IDMonitor    string `json:"idMonitor"`    // 567
+	UserSchermo  string `json:"userSchermo"`  // cucciniello
+
+	// FTP
+	FTPServer    string `json:"ftpServer"`    // da provisioning o servlet
+	FTPPort      int    `json:"ftpPort"`      // 21
+	FTPUsername  string `json:"ftpUsername"`
+	FTPPassword  string `json:"ftpPassword"`  // (encrypted)
+	FTPDirectory string `json:"ftpDirectory"` // /
+
+	// Configurazione player
+	ConnectionMode   int    `json:"connectionMode"`   // 3 = PASV
+	VideoQuality     int    `json:"videoQuality"`     // 34
+	Delay            int    `json:"delay"`            // 20000 ms
+	SecondiCache     int    `json:"secondiCache"`     // 5
+	SecondiTolleranza int   `json:"secondiTolleranza"` // 12
+
+	// Paths
+	DataDir      string `json:"dataDir"`      // /data/data/com.spotlive.player/files
+	MediaDir     string `json:"mediaDir"`     // dataDir/media
+	CacheDir     string `json:"cacheDir"`     // dataDir/cache
+
+	// Sicurezza media
+	MediaPrefixes []string `json:"mediaPrefixes"` // ["upload/"] path remoti sempre consentiti
+}
+
+var (
+	configPath    string
+	dataDir       string
+
+	// futureVersion versione schema più recente trovata su disco: il file
+	// non va sovrascritto (0 = nessun blocco)
+	futureVersion int
+)
+
+// SetDataDir imposta directory dati (chiamato da Android)
+func SetDataDir(dir string) {
+	dataDir = dir
+	configPath = filepath.Join(dir, "config.json")
+	resetKey()
+}
+
+// DataRoot ritorna la directory dati impostata da SetDataDir (non
+// modificabile dalle API, a differenza di Config.DataDir)
+func DataRoot() string {
+	return dataDir
+}
+
+// Load carica configurazione da file. Se il file principale non è
+// leggibile (scrittura interrotta, corruzione) usa config.json.bak.
+func Load() (*Config, error) {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+
+	if configPath == "" {
+		return nil, apperr.New(CodeDataDirNotSet, "data directory not set")
+	}
+
+	cfg, keys, err := loadFile(configPath)
+	if err != nil {
+		// Versione futura: il backup è più vecchio, non usarlo
+		if apperr.CodeOf(err) == CodeUnsupportedVersion {
+			return nil, err
+		}
+
+		bak, bakKeys, bakErr := loadFile(backupPath())
+		if bakErr != nil {
+			if errors.Is(err, os.ErrNotExist) && errors.Is(bakErr, os.ErrNotExist) {
+				// Config non esiste, ritorna default
+				setFileKeys(nil)
+				publish(GetDefault())
+				return Get(), nil
+			}
+			return nil, err
+		}
+
+		log.Printf("Warning: config unreadable (%v), restored from backup", err)
+		cfg, keys = bak, bakKeys
+		if err := writeEncrypted(cfg); err != nil {
+			log.Printf("Warning: config restore failed: %v", err)
+		}
+	}
+
+	setFileKeys(keys)
+	publish(cfg)
+	return Get(), nil
+}
+
+// loadFile legge, decifra e migra un file config
+func loadFile(path string) (*Config, map[string]bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, apperr.Wrap(err, CodeReadFailed, "config read failed").
+			WithDetail("path", path)
+	}
+
+	// Decripta (formato legacy AES-CFB accettato solo fino alla prima
+	// scrittura v2, vedi legacyAllowed)
+	decrypted, legacy, err := decrypt(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Backup di migrazione: il formato legacy (chiave pubblica nel
+	// sorgente) viene ricifrato con la chiave del dispositivo
+	original := data
+	if legacy {
+		if original, err = encrypt(decrypted); err != nil {
+			return nil, nil, apperr.Wrap(err, CodeWriteFailed, "config encrypt failed")
+		}
+	}
+
+	// Migra schema alla versione corrente (versioni future rifiutate)
+	var raw map[string]interface{}
+	if err := json.Unmarshal(decrypted, &raw); err != nil {
+		return nil, nil, apperr.Wrap(err, CodeParseFailed, "config parse failed")
+	}
+	fromVersion, err := migrate(raw)
+	if err != nil {
+		if fromVersion > SchemaVersion {
+			futureVersion = fromVersion
+		}
+		return nil, nil, err
+	}
+	futureVersion = 0
+	migrated := fromVersion != SchemaVersion
+	if migrated {
+		if decrypted, err = json.Marshal(raw); err != nil {
+			return nil, nil, apperr.Wrap(err, CodeParseFailed, "config parse failed")
+		}
+	}
+
+	// Campi assenti nel file mantengono i default
+	cfg := *GetDefault()
+	if err := json.Unmarshal(decrypted, &cfg); err != nil {
+		return nil, nil, apperr.Wrap(err, CodeParseFailed, "config parse failed")
+	}
+
+	// Riscrive file migrato (schema o formato legacy AES-CFB), con backup dell'originale
+	if legacy || migrated {
+		backup := fmt.Sprintf("%s.v%d.bak", configPath, fromVersion)
+		if err := writeFileAtomic(backup, original, 0600); err != nil {
+			log.Printf("Warning: config backup failed: %v", err)
+		} else if err := writeEncrypted(&cfg); err != nil {
+			log.Printf("Warning: config migration failed: %v", err)
+		} else {
+			log.Printf("Config migrated from schema v%d to v%d (backup: %s)", fromVersion, SchemaVersion, backup)
+		}
+	}
+
+	keys := make(map[string]bool, len(raw))
+	for k := range raw {
+		keys[k] = true
+	}
+	return &cfg, keys, nil
+}
+
+// backupPath ritorna path della copia dell'ultima config valida
+func backupPath() string {
+	return configPath + ".bak"
+}
+
+// Reset elimina config, backup e file temporanei e torna ai default (stato
+// di setup). Segreto del dispositivo e token admin vengono mantenuti.
+func Reset() ([]string, error) {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+
+	if configPath == "" {
+		return nil, apperr.New(CodeDataDirNotSet, "data directory not set")
+	}
+
+	paths := []string{configPath, backupPath()}
+	// Backup di migrazione e temporanei di writeFileAtomic (config.json.tmp-*)
+	for _, pattern := range []string{configPath + ".v*.bak", configPath + ".tmp-*"} {
+		if matches, err := filepath.Glob(pattern); err == nil {
+			paths = append(paths, matches...)
+		}
+	}
+
+	var removed []string
+	for _, p := range paths {
+		err := os.Remove(p)
+		if err == nil {
+			removed = append(removed, p)
+			continue
+		}
+		if !os.IsNotExist(err) {
+			return removed, apperr.Wrap(err, CodeWriteFailed, "config reset failed").
+				WithDetail("path", p)
+		}
+	}
+	if len(removed) > 0 {
+		syncDir(filepath.Dir(configPath))
+	}
+
+	futureVersion = 0
+	setFileKeys(nil)
+	publish(GetDefault())
+	return removed, nil
+}
+
+// Save salva configurazione su file (encrypted)
+func Save(cfg *Config) error {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+
+	if configPath == "" {
+		return apperr.New(CodeDataDirNotSet, "data directory not set")
+	}
+	if futureVersion > 0 {
+		return apperr.New(CodeUnsupportedVersion, "config written by a newer version, refusing to overwrite").
+			WithDetail("schemaVersion", futureVersion)
+	}
+
+	// Assicura che le directory esistano
+	if cfg.DataDir == "" {
+		cfg.DataDir = dataDir
+	}
+	cfg.MediaDir = filepath.Join(cfg.DataDir, "media")
+	cfg.CacheDir = filepath.Join(cfg.DataDir, "cache")
+	os.MkdirAll(cfg.MediaDir, 0755)
+	os.MkdirAll(cfg.CacheDir, 0755)
+
+	// Valori da env/flag non vanno scritti nel file
+	stripOverlay(cfg)
+
+	cfg.SchemaVersion = SchemaVersion
+	if err := writeEncrypted(cfg); err != nil {
+		return err
+	}
+
+	setFileKeys(allFields())
+	publish(cfg)
+	return nil
+}
+
+// writeEncrypted serializza, cripta e scrive config su disco
+func writeEncrypted(cfg *Config) error {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return apperr.Wrap(err, CodeWriteFailed, "config encode failed")
+	}
+
+	// Cripta
+	encrypted, err := encrypt(data)
+	if err != nil {
+		return apperr.Wrap(err, CodeWriteFailed, "config encrypt failed")
+	}
+
+	// Conserva versione precedente solo se valida (mai sovrascrivere un
+	// backup buono con un file principale corrotto); un file legacy viene
+	// ricifrato per non lasciarlo leggibile con la chiave pubblica
+	if current, err := os.ReadFile(configPath); err == nil {
+		if plain, legacy, err := decrypt(current); err == nil {
+			if legacy {
+				current, err = encrypt(plain)
+			}
+			if err != nil {
+				log.Printf("Warning: config backup failed: %v", err)
+			} else if err := writeFileAtomic(backupPath(), current, 0600); err != nil {
+				log.Printf("Warning: config backup failed: %v", err)
+			}
+		}
+	}
+
+	// Salva (temp + fsync + rename)
+	if err := writeFileAtomic(configPath, encrypted, 0600); err != nil {
+		return apperr.Wrap(err, CodeWriteFailed, "config write failed").
+			WithDetail("path", configPath)
+	}
+	if err := markMigrated(); err != nil {
+		return apperr.Wrap(err, CodeWriteFailed, "config migration marker write failed")
+	}
+	return nil
+}
+
+// GetDefault ritorna configurazione di default
+func GetDefault() *Config {
+	return &Config{
+		SchemaVersion:     SchemaVersion,
+		ServerURL:         "http://80.88.90.214:80",
+		ServletPath:       DefaultServletPath,
+		ProtocolVersion:   DefaultProtocolVersion,
+		AuthMode:          AuthAuto,
+		HTTPConnectTimeout: 10,
+		HTTPReadTimeout:    30,
+		MaxScheduleBytes:   DefaultMaxScheduleBytes,
+		// Nessuna credenziale nel binario: FTP da provisioning o dal servlet
+		FTPPort:          21,
+		FTPDirectory:     "/",
+		ConnectionMode:   3,
+		VideoQuality:     34,
+		Delay:            20000,
+		SecondiCache:     5,
+		SecondiTolleranza: 12,
+		MediaPrefixes:     []string{"upload/"},
+		DataDir:           dataDir,
+		MediaDir:          filepath.Join(dataDir, "media"),
+		CacheDir:          filepath.Join(dataDir, "cache"),
+	}
+}
+
+// IsConfigured verifica se app è configurata
+func IsConfigured() bool {
+	cfg := Get()
+	return cfg.Username != "" && cfg.Password != "" && cfg.IDMonitor != ""
+}
+
+// CheckConfigured ritorna errore tipizzato con i campi obbligatori mancanti
+func CheckConfigured() error {
+	cfg := Get()
+
+	var missing []string
+	if cfg.Username == "" {
+		missing = append(missing, "username")
+	}
+	if cfg.Password == "" {
+		missing = append(missing, "password")
+	}
+	if cfg.IDMonitor == "" {
+		missing = append(missing, "idMonitor")
+	}
+
+	if len(missing) > 0 {
+		return apperr.New(CodeNotConfigured, "app not configured").
+			WithDetail("missing", missing)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResetRemovesTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	SetDataDir(dir)
+
+	cfg := GetDefault()
+	cfg.Username = "user"
+	if err := Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+	// Temporaneo lasciato da una scrittura interrotta
+	stale := filepath.Join(dir, "config.json.tmp-123456")
+	if err := os.WriteFile(stale, []byte("slcfg:v2:stale"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale temp file survived reset: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, keyFileName)); err != nil {
+		t.Errorf("device secret removed by reset: %v", err)
+	}
+}
+
+func TestValidateDoesNotCreateDataDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "new", "data")
+
+	cfg := GetDefault()
+	cfg.DataDir = dir
+	if errs := cfg.Validate(); errs["dataDir"] != "" {
+		t.Fatalf("dataDir under writable parent rejected: %s", errs["dataDir"])
+	}
+	if _, err := os.Stat(filepath.Dir(dir)); !os.IsNotExist(err) {
+		t.Errorf("Validate created directories: %v", err)
+	}
+
+	// Un file nel percorso impedisce la creazione
+	file := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(file, []byte("x"), 0600)
+	cfg.DataDir = filepath.Join(file, "data")
+	if errs := cfg.Validate(); errs["dataDir"] == "" {
+		t.Error("dataDir below a regular file accepted")
+	}
+}
+
+// saveUser salva config con lo username indicato
+func saveUser(t *testing.T, username string) {
+	t.Helper()
+
+	cfg := GetDefault()
+	cfg.Username = username
+	if err := Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// backupUser ritorna lo username salvato in config.json.bak
+func backupUser(t *testing.T) string {
+	t.Helper()
+
+	cfg, _, err := loadFile(backupPath())
+	if err != nil {
+		t.Fatalf("backup unreadable: %v", err)
+	}
+	return cfg.Username
+}
+
+func TestLoadRestoresCorruptConfigFromBackup(t *testing.T) {
+	for name, corrupt := range map[string]func([]byte) []byte{
+		"truncated": func(b []byte) []byte { return b[:len(b)/2] },
+		"garbage":   func([]byte) []byte { return []byte("slcfg:v2:not-base64!") },
+		"empty":     func([]byte) []byte { return nil },
+	} {
+		t.Run(name, func(t *testing.T) {
+			SetDataDir(t.TempDir())
+			saveUser(t, "first")
+			saveUser(t, "second") // backup = first
+
+			data, err := os.ReadFile(configPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(configPath, corrupt(data), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.Username != "first" {
+				t.Errorf("restored username = %q, want first", cfg.Username)
+			}
+
+			// File principale riscritto e di nuovo leggibile
+			if restored, _, err := loadFile(configPath); err != nil || restored.Username != "first" {
+				t.Errorf("main file after restore = %v, %v", restored, err)
+			}
+			if got := backupUser(t); got != "first" {
+				t.Errorf("backup username = %q, want first", got)
+			}
+		})
+	}
+}
+
+func TestCorruptConfigNeverOverwritesBackup(t *testing.T) {
+	SetDataDir(t.TempDir())
+	saveUser(t, "first")
+	saveUser(t, "second") // backup = first
+
+	if err := os.WriteFile(configPath, []byte("corrupted"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Scrittura con file principale illeggibile: il backup buono resta
+	saveUser(t, "third")
+	if got := backupUser(t); got != "first" {
+		t.Errorf("backup username = %q, want first", got)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Username != "third" {
+		t.Errorf("username = %q, want third", cfg.Username)
+	}
+}
+
+func TestLoadWithoutFilesReturnsDefaults(t *testing.T) {
+	SetDataDir(t.TempDir())
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	def := GetDefault()
+	if cfg.ServerURL != def.ServerURL || cfg.Username != "" || cfg.ServletPath != def.ServletPath {
+		t.Errorf("config = %+v, want defaults", cfg)
+	}
+	if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+		t.Errorf("Load created config file: %v", err)
+	}
+}
+
+func TestLoadFailsWhenBothFilesCorrupt(t *testing.T) {
+	SetDataDir(t.TempDir())
+
+	if err := os.WriteFile(configPath, []byte("corrupted"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(backupPath(), []byte("corrupted"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(); err == nil {
+		t.Error("Load succeeded with corrupt config and backup")
+	}
+}
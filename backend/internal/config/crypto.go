@@ -0,0 +1,256 @@
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"spotlive-server/internal/apperr"
+	"strings"
+	"sync"
+)
+
+// Formato file config: "<header>" + base64(nonce || ciphertext+tag).
+// L'header è anche dato autenticato (AAD): cambiarlo invalida il file.
+const (
+	formatV2       = "slcfg:v2"
+	formatV2Header = formatV2 + ":"
+
+	// keyFileName segreto del dispositivo generato al primo avvio (0600)
+	keyFileName = "config.key"
+	// keyInfo contesto per la derivazione della chiave di cifratura config
+	keyInfo = "spotlive-config-aes-gcm-v2"
+	// migratedFileName marcatore scritto con il primo file v2: da quel
+	// momento il formato legacy (chiave pubblica) non è più accettato
+	migratedFileName = "config.migrated"
+)
+
+// legacyKey chiave statica delle versioni precedenti (solo per migrazione)
+var legacyKey = []byte("spotlive2024key!")
+
+var (
+	keyMu       sync.Mutex
+	keyMaterial []byte // fornito dall'esterno (keystore Android via flag/env)
+	derivedKey  []byte
+)
+
+// SetKeyMaterial imposta segreto del dispositivo fornito dall'esterno
+// (es. keystore Android). Se vuoto si usa il segreto generato in data dir.
+func SetKeyMaterial(secret string) {
+	keyMu.Lock()
+	defer keyMu.Unlock()
+
+	if secret == "" {
+		keyMaterial = nil
+	} else {
+		keyMaterial = []byte(secret)
+	}
+	derivedKey = nil
+}
+
+// resetKey invalida la chiave derivata (data dir cambiata)
+func resetKey() {
+	keyMu.Lock()
+	derivedKey = nil
+	keyMu.Unlock()
+}
+
+// deviceKey ritorna chiave AES-256 derivata dal segreto del dispositivo
+func deviceKey() ([]byte, error) {
+	keyMu.Lock()
+	defer keyMu.Unlock()
+
+	if derivedKey != nil {
+		return derivedKey, nil
+	}
+
+	secret := keyMaterial
+	if secret == nil {
+		var err error
+		secret, err = loadOrCreateSecret(filepath.Join(dataDir, keyFileName))
+		if err != nil {
+			return nil, apperr.Wrap(err, CodeKeyFailed, "device key unavailable")
+		}
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(keyInfo))
+	derivedKey = mac.Sum(nil)
+	return derivedKey, nil
+}
+
+// loadOrCreateSecret legge il segreto del dispositivo o ne genera uno nuovo
+func loadOrCreateSecret(path string) ([]byte, error) {
+	if dataDir == "" {
+		return nil, errors.New("data directory not set")
+	}
+
+	secret, err := os.ReadFile(path)
+	if err == nil {
+		if len(secret) < 32 {
+			return nil, fmt.Errorf("device secret %s too short", path)
+		}
+		return secret, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	secret = make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, secret); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	// O_EXCL: non sovrascrivere un segreto creato nel frattempo
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(secret); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return secret, f.Close()
+}
+
+// encrypt cifra con AES-256-GCM e chiave del dispositivo
+func encrypt(plaintext []byte) ([]byte, error) {
+	key, err := deviceKey()
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, plaintext, []byte(formatV2))
+	return []byte(formatV2Header + base64.StdEncoding.EncodeToString(sealed)), nil
+}
+
+// decrypt decifra file config. legacy=true se il file usa il vecchio
+// formato AES-CFB con chiave statica (da riscrivere nel nuovo formato).
+func decrypt(data []byte) (plaintext []byte, legacy bool, err error) {
+	data = bytes.TrimSpace(data)
+
+	if !bytes.HasPrefix(data, []byte(formatV2Header)) {
+		// Header sconosciuto con prefisso di formato: versione futura
+		if bytes.HasPrefix(data, []byte("slcfg:")) {
+			version := strings.SplitN(string(data), ":", 3)[1]
+			return nil, false, apperr.New(CodeDecryptFailed, "unsupported config format").
+				WithDetail("format", version)
+		}
+		if !legacyAllowed() {
+			return nil, true, apperr.New(CodeLegacyRejected, "legacy config format no longer accepted")
+		}
+		plaintext, err := decryptLegacy(data)
+		return plaintext, true, err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(string(data[len(formatV2Header):]))
+	if err != nil {
+		return nil, false, apperr.Wrap(err, CodeTampered, "config file corrupted")
+	}
+
+	key, err := deviceKey()
+	if err != nil {
+		return nil, false, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, false, apperr.Wrap(err, CodeDecryptFailed, "config decrypt failed")
+	}
+
+	if len(sealed) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, false, apperr.New(CodeTampered, "config file truncated")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err = gcm.Open(nil, nonce, ciphertext, []byte(formatV2))
+	if err != nil {
+		// Tag non valido: file modificato o chiave del dispositivo diversa
+		return nil, false, apperr.Wrap(err, CodeTampered, "config authentication failed")
+	}
+	return plaintext, false, nil
+}
+
+// legacyAllowed il formato legacy è accettato solo prima della migrazione:
+// senza marcatore e senza segreto del dispositivo (creato dal primo file v2)
+func legacyAllowed() bool {
+	if dataDir == "" {
+		return false
+	}
+	for _, name := range []string{migratedFileName, keyFileName} {
+		if _, err := os.Stat(filepath.Join(dataDir, name)); !os.IsNotExist(err) {
+			return false
+		}
+	}
+	return true
+}
+
+// markMigrated registra che la config è stata scritta nel formato v2
+func markMigrated() error {
+	path := filepath.Join(dataDir, migratedFileName)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	return writeFileAtomic(path, []byte(formatV2+"\n"), 0600)
+}
+
+// decryptLegacy decifra vecchio formato base64(iv || AES-CFB). Senza
+// autenticazione: il risultato deve essere JSON valido per essere accettato.
+func decryptLegacy(data []byte) ([]byte, error) {
+	decoded, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return nil, apperr.Wrap(err, CodeDecryptFailed, "config decrypt failed")
+	}
+
+	block, err := aes.NewCipher(legacyKey)
+	if err != nil {
+		return nil, apperr.Wrap(err, CodeDecryptFailed, "config decrypt failed")
+	}
+
+	if len(decoded) < aes.BlockSize {
+		return nil, apperr.New(CodeDecryptFailed, "ciphertext too short")
+	}
+
+	iv := decoded[:aes.BlockSize]
+	decoded = decoded[aes.BlockSize:]
+
+	stream := cipher.NewCFBDecrypter(block, iv)
+	stream.XORKeyStream(decoded, decoded)
+
+	if !json.Valid(decoded) {
+		return nil, apperr.New(CodeTampered, "legacy config corrupted")
+	}
+	return decoded, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
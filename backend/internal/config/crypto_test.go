@@ -0,0 +1,101 @@
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"spotlive-server/internal/apperr"
+	"testing"
+)
+
+// legacyEncrypt cifra come le versioni precedenti (AES-CFB, chiave statica)
+func legacyEncrypt(t *testing.T, raw map[string]interface{}) []byte {
+	t.Helper()
+
+	plain, err := json.Marshal(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := aes.NewCipher(legacyKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]byte, aes.BlockSize+len(plain))
+	if _, err := io.ReadFull(rand.Reader, out[:aes.BlockSize]); err != nil {
+		t.Fatal(err)
+	}
+	cipher.NewCFBEncrypter(block, out[:aes.BlockSize]).XORKeyStream(out[aes.BlockSize:], plain)
+	return []byte(base64.StdEncoding.EncodeToString(out))
+}
+
+func TestLegacyConfigRejectedAfterMigration(t *testing.T) {
+	SetDataDir(t.TempDir())
+
+	legacy := legacyEncrypt(t, map[string]interface{}{"username": "user", "idMonitor": "567"})
+	if err := os.WriteFile(configPath, legacy, 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("legacy migration: %v", err)
+	}
+	if cfg.Username != "user" {
+		t.Fatalf("migrated username = %q", cfg.Username)
+	}
+
+	// Config contraffatta con la chiave pubblica: rifiutata con errore tipizzato
+	forged := legacyEncrypt(t, map[string]interface{}{"username": "attacker", "serverUrl": "http://evil.example"})
+	if err := os.WriteFile(configPath, forged, 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = Load()
+	if apperr.CodeOf(err) != CodeLegacyRejected {
+		t.Fatalf("Load forged legacy = %v, %v; want %s", cfg, err, CodeLegacyRejected)
+	}
+}
+
+func TestLegacyPlaintextNotRecoverableAfterMigration(t *testing.T) {
+	SetDataDir(t.TempDir())
+
+	legacy := legacyEncrypt(t, map[string]interface{}{"username": "user", "password": "secret"})
+	if err := os.WriteFile(configPath, legacy, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(); err != nil {
+		t.Fatalf("legacy migration: %v", err)
+	}
+
+	// Nessun file rimasto su disco decifrabile con la chiave pubblica
+	entries, err := os.ReadDir(filepath.Dir(configPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(filepath.Dir(configPath), e.Name()))
+		if err != nil {
+			continue
+		}
+		if plain, err := decryptLegacy(bytes.TrimSpace(data)); err == nil {
+			t.Errorf("%s readable with legacy key: %s", e.Name(), plain)
+		}
+	}
+
+	// Il backup di migrazione resta leggibile con la chiave del dispositivo
+	backup, err := os.ReadFile(configPath + ".v1.bak")
+	if err != nil {
+		t.Fatalf("backup missing: %v", err)
+	}
+	plain, legacyFormat, err := decrypt(backup)
+	if err != nil || legacyFormat {
+		t.Fatalf("backup decrypt = legacy %v, %v", legacyFormat, err)
+	}
+	if !bytes.Contains(plain, []byte(`"secret"`)) {
+		t.Errorf("backup content = %s", plain)
+	}
+}
@@ -0,0 +1,21 @@
+package config
+
+import "spotlive-server/internal/apperr"
+
+// Codici errore configurazione
+const (
+	CodeDataDirNotSet      apperr.Code = "CONFIG_DATA_DIR_NOT_SET"
+	CodeReadFailed         apperr.Code = "CONFIG_READ_FAILED"
+	CodeDecryptFailed      apperr.Code = "CONFIG_DECRYPT_FAILED"
+	CodeTampered           apperr.Code = "CONFIG_TAMPERED"
+	CodeLegacyRejected     apperr.Code = "CONFIG_LEGACY_REJECTED"
+	CodeKeyFailed          apperr.Code = "CONFIG_KEY_UNAVAILABLE"
+	CodeParseFailed        apperr.Code = "CONFIG_PARSE_FAILED"
+	CodeWriteFailed        apperr.Code = "CONFIG_WRITE_FAILED"
+	CodeNotConfigured      apperr.Code = "CONFIG_NOT_CONFIGURED"
+	CodeMissingFields      apperr.Code = "CONFIG_MISSING_FIELDS"
+	CodeInvalidFields      apperr.Code = "CONFIG_INVALID_FIELDS"
+	CodeUnsupportedVersion apperr.Code = "CONFIG_UNSUPPORTED_VERSION"
+	CodeBundleInvalid      apperr.Code = "CONFIG_BUNDLE_INVALID"
+	CodeBundleAuth         apperr.Code = "CONFIG_BUNDLE_AUTH_FAILED"
+)
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"spotlive-server/internal/apperr"
+)
+
+// SchemaVersion versione corrente dello schema config persistito.
+// Ogni incremento richiede una migrazione in migrations.
+const SchemaVersion = 2
+
+// migration trasforma il JSON grezzo dalla versione from a from+1
+type migration struct {
+	from        int
+	description string
+	apply       func(raw map[string]interface{}) error
+}
+
+// migrations catena ordinata delle migrazioni (una per versione)
+var migrations = []migration{
+	{
+		from:        1,
+		description: "add mediaPrefixes allow-list, normalize ftpDirectory",
+		apply: func(raw map[string]interface{}) error {
+			if _, ok := raw["mediaPrefixes"]; !ok {
+				raw["mediaPrefixes"] = []interface{}{"upload/"}
+			}
+			if dir, _ := raw["ftpDirectory"].(string); dir == "" {
+				raw["ftpDirectory"] = "/"
+			}
+			return nil
+		},
+	},
+}
+
+// schemaVersionOf legge versione schema (file senza campo = v1, pre-versioning)
+func schemaVersionOf(raw map[string]interface{}) (int, error) {
+	v, ok := raw["schemaVersion"]
+	if !ok {
+		return 1, nil
+	}
+	n, ok := v.(float64)
+	if !ok || n < 1 || n != float64(int(n)) {
+		return 0, apperr.New(CodeParseFailed, "invalid schemaVersion").
+			WithDetail("schemaVersion", v)
+	}
+	return int(n), nil
+}
+
+// migrate porta raw alla versione corrente applicando la catena di
+// migrazioni. Ritorna la versione di partenza. Versioni future vengono
+// rifiutate senza modificare nulla (il file potrebbe venire da un
+// aggiornamento successivo poi annullato).
+func migrate(raw map[string]interface{}) (int, error) {
+	from, err := schemaVersionOf(raw)
+	if err != nil {
+		return 0, err
+	}
+
+	if from > SchemaVersion {
+		return from, apperr.New(CodeUnsupportedVersion, "config written by a newer version").
+			WithDetail("schemaVersion", from).
+			WithDetail("supported", SchemaVersion)
+	}
+
+	version := from
+	for _, m := range migrations {
+		if m.from != version {
+			continue
+		}
+		if err := m.apply(raw); err != nil {
+			return from, apperr.Wrap(err, CodeParseFailed, fmt.Sprintf("config migration v%d failed", m.from))
+		}
+		version++
+		raw["schemaVersion"] = version
+	}
+
+	if version != SchemaVersion {
+		return from, apperr.New(CodeParseFailed, "missing config migration").
+			WithDetail("schemaVersion", version)
+	}
+	return from, nil
+}
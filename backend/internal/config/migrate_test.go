@@ -0,0 +1,174 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrationChainComplete(t *testing.T) {
+	for v := 1; v < SchemaVersion; v++ {
+		n := 0
+		for _, m := range migrations {
+			if m.from == v {
+				n++
+			}
+		}
+		if n != 1 {
+			t.Errorf("schema v%d has %d migrations; want exactly 1", v, n)
+		}
+	}
+}
+
+func TestMigrateV1ToV2(t *testing.T) {
+	raw := map[string]interface{}{
+		"username":     "user",
+		"ftpDirectory": "",
+	}
+
+	from, err := migrate(raw)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if from != 1 {
+		t.Errorf("from = %d; want 1", from)
+	}
+	if raw["schemaVersion"] != 2 {
+		t.Errorf("schemaVersion = %v; want 2", raw["schemaVersion"])
+	}
+	if dir := raw["ftpDirectory"]; dir != "/" {
+		t.Errorf("ftpDirectory = %v; want /", dir)
+	}
+	prefixes, _ := raw["mediaPrefixes"].([]interface{})
+	if len(prefixes) != 1 || prefixes[0] != "upload/" {
+		t.Errorf("mediaPrefixes = %v; want [upload/]", raw["mediaPrefixes"])
+	}
+}
+
+func TestMigrateV1ToV2KeepsExistingValues(t *testing.T) {
+	raw := map[string]interface{}{
+		"ftpDirectory":  "/media",
+		"mediaPrefixes": []interface{}{"spot/"},
+	}
+
+	if _, err := migrate(raw); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if dir := raw["ftpDirectory"]; dir != "/media" {
+		t.Errorf("ftpDirectory = %v; want /media", dir)
+	}
+	prefixes, _ := raw["mediaPrefixes"].([]interface{})
+	if len(prefixes) != 1 || prefixes[0] != "spot/" {
+		t.Errorf("mediaPrefixes = %v; want [spot/]", raw["mediaPrefixes"])
+	}
+}
+
+func TestMigrateCurrentVersionIsNoop(t *testing.T) {
+	raw := map[string]interface{}{"schemaVersion": float64(SchemaVersion)}
+
+	from, err := migrate(raw)
+	if err != nil || from != SchemaVersion {
+		t.Fatalf("migrate = %d, %v; want %d, nil", from, err, SchemaVersion)
+	}
+	if len(raw) != 1 {
+		t.Errorf("raw modified: %v", raw)
+	}
+}
+
+func TestMigrateRefusesFutureVersion(t *testing.T) {
+	raw := map[string]interface{}{"schemaVersion": float64(SchemaVersion + 1)}
+
+	if _, err := migrate(raw); err == nil {
+		t.Fatal("future schema accepted")
+	}
+}
+
+func TestMigrateRejectsInvalidVersion(t *testing.T) {
+	for _, v := range []interface{}{"2", float64(0), float64(1.5), nil} {
+		raw := map[string]interface{}{"schemaVersion": v}
+		if _, err := migrate(raw); err == nil {
+			t.Errorf("schemaVersion %v accepted", v)
+		}
+	}
+}
+
+// writeTestConfig scrive config cifrata con il JSON indicato
+func writeTestConfig(t *testing.T, raw map[string]interface{}) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := encrypt(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configPath, encrypted, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return encrypted
+}
+
+func TestLoadMigratesAndKeepsBackup(t *testing.T) {
+	SetDataDir(t.TempDir())
+	original := writeTestConfig(t, map[string]interface{}{
+		"username":  "user",
+		"password":  "secret",
+		"idMonitor": "567",
+	})
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.SchemaVersion != SchemaVersion || cfg.Username != "user" {
+		t.Errorf("loaded config = %+v", cfg)
+	}
+
+	backup, err := os.ReadFile(configPath + ".v1.bak")
+	if err != nil {
+		t.Fatalf("backup missing: %v", err)
+	}
+	if string(backup) != string(original) {
+		t.Error("backup differs from original file")
+	}
+
+	// Il file riscritto è già alla versione corrente
+	data, _ := os.ReadFile(configPath)
+	plain, _, err := decrypt(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw map[string]interface{}
+	json.Unmarshal(plain, &raw)
+	if raw["schemaVersion"] != float64(SchemaVersion) {
+		t.Errorf("persisted schemaVersion = %v", raw["schemaVersion"])
+	}
+}
+
+func TestLoadRefusesFutureVersionWithoutOverwriting(t *testing.T) {
+	dir := t.TempDir()
+	SetDataDir(dir)
+	original := writeTestConfig(t, map[string]interface{}{
+		"schemaVersion": SchemaVersion + 1,
+		"username":      "user",
+	})
+	defer func() { futureVersion = 0 }()
+
+	if _, err := Load(); err == nil {
+		t.Fatal("future config loaded")
+	}
+
+	cfg := GetDefault()
+	cfg.DataDir = dir
+	if err := Save(cfg); err == nil {
+		t.Fatal("Save overwrote future config")
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "config.json"))
+	if string(data) != string(original) {
+		t.Error("future config modified")
+	}
+}
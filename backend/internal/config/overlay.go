@@ -0,0 +1,257 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Configurazione a livelli (priorità crescente):
+//
+//	default < file (config.json) < variabili d'ambiente < flag -set
+//
+// Ogni campo di Config può essere impostato con SPOTLIVE_<CAMPO> dove
+// <CAMPO> è il nome JSON in maiuscolo con "_" tra le parole
+// (serverUrl → SPOTLIVE_SERVER_URL, ftpPort → SPOTLIVE_FTP_PORT,
+// mediaPrefixes → SPOTLIVE_MEDIA_PREFIXES come lista separata da virgole),
+// oppure da riga di comando con -set serverUrl=http://host:80 (ripetibile).
+// I valori sovrapposti non vengono mai scritti nel file config.
+
+// Sorgenti di un valore di configurazione
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// EnvPrefix prefisso delle variabili d'ambiente di configurazione
+const EnvPrefix = "SPOTLIVE_"
+
+// overlayValue valore imposto da env o flag
+type overlayValue struct {
+	value  reflect.Value
+	source string
+	origin string // nome variabile o "-set campo"
+}
+
+// FieldInfo valore effettivo di un campo e sua provenienza
+type FieldInfo struct {
+	Field  string      `json:"field"`
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
+	Env    string      `json:"env"`
+	Origin string      `json:"origin,omitempty"`
+}
+
+// overlay valori sovrapposti per campo JSON (protetto da mu)
+var overlay = map[string]overlayValue{}
+
+// EnvName ritorna la variabile d'ambiente di un campo (serverUrl → SPOTLIVE_SERVER_URL)
+func EnvName(field string) string {
+	var b strings.Builder
+	b.WriteString(EnvPrefix)
+	for i, r := range field {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := rune(field[i-1])
+			if !unicode.IsUpper(prev) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// overlayFields campi JSON sovrapponibili → indice del campo in Config
+func overlayFields() map[string]int {
+	fields := make(map[string]int)
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		name := jsonName(t.Field(i))
+		if name == "" || name == "-" || name == "schemaVersion" {
+			continue
+		}
+		fields[name] = i
+	}
+	return fields
+}
+
+// SetOverlay legge le variabili SPOTLIVE_* da environ (formato os.Environ)
+// e i valori dei flag (campo JSON → valore). I flag prevalgono sull'ambiente.
+func SetOverlay(environ []string, flags map[string]string) error {
+	env := make(map[string]string)
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(k, EnvPrefix) {
+			env[k] = v
+		}
+	}
+
+	fields := overlayFields()
+	t := reflect.TypeOf(Config{})
+	values := map[string]overlayValue{}
+
+	for name, idx := range fields {
+		envName := EnvName(name)
+		raw, ok := env[envName]
+		if !ok {
+			continue
+		}
+		v, err := parseValue(t.Field(idx).Type, raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", envName, err)
+		}
+		values[name] = overlayValue{value: v, source: SourceEnv, origin: envName}
+	}
+
+	for name, raw := range flags {
+		idx, ok := fields[name]
+		if !ok {
+			return fmt.Errorf("-set %s: unknown config field", name)
+		}
+		v, err := parseValue(t.Field(idx).Type, raw)
+		if err != nil {
+			return fmt.Errorf("-set %s: %w", name, err)
+		}
+		values[name] = overlayValue{value: v, source: SourceFlag, origin: "-set " + name}
+	}
+
+	mu.Lock()
+	overlay = values
+	mu.Unlock()
+
+	// Ricalcola configurazione effettiva
+	if p := persistedSnapshot(); p != nil {
+		publish(p)
+	}
+	return nil
+}
+
+// LoadOverlayFromEnv è SetOverlay con l'ambiente del processo
+func LoadOverlayFromEnv(flags map[string]string) error {
+	return SetOverlay(os.Environ(), flags)
+}
+
+// setFileKeys registra i campi presenti nel file config
+func setFileKeys(keys map[string]bool) {
+	mu.Lock()
+	fileKeys = keys
+	mu.Unlock()
+}
+
+// allFields ritorna tutti i campi JSON di Config (file appena scritto)
+func allFields() map[string]bool {
+	keys := map[string]bool{"schemaVersion": true}
+	for name := range overlayFields() {
+		keys[name] = true
+	}
+	return keys
+}
+
+// parseValue converte stringa nel tipo del campo
+func parseValue(t reflect.Type, raw string) (reflect.Value, error) {
+	switch t.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(raw), nil
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid integer %q", raw)
+		}
+		return reflect.ValueOf(n), nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.String {
+			var list []string
+			for _, item := range strings.Split(raw, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+			return reflect.ValueOf(list), nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("unsupported field type %s", t)
+}
+
+// applyOverlay applica i valori sovrapposti a cfg (chiamare con mu acquisito)
+func applyOverlay(cfg *Config) {
+	if len(overlay) == 0 {
+		return
+	}
+	cv := reflect.ValueOf(cfg).Elem()
+	fields := overlayFields()
+	for name, ov := range overlay {
+		cv.Field(fields[name]).Set(ov.value)
+	}
+}
+
+// stripOverlay riporta i campi sovrapposti al valore persistito, così che
+// Save non scriva nel file valori provenienti da env o flag
+func stripOverlay(cfg *Config) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if len(overlay) == 0 {
+		return
+	}
+	base := persisted
+	if base == nil {
+		base = GetDefault()
+	}
+	cv := reflect.ValueOf(cfg).Elem()
+	bv := reflect.ValueOf(base).Elem()
+	fields := overlayFields()
+	for name := range overlay {
+		idx := fields[name]
+		cv.Field(idx).Set(bv.Field(idx))
+	}
+}
+
+// OverriddenBy ritorna origine (variabile o flag) se il campo è sovrapposto
+func OverriddenBy(field string) (string, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	ov, ok := overlay[field]
+	return ov.origin, ok
+}
+
+// Describe ritorna valore effettivo e provenienza di ogni campo (segreti mascherati)
+func Describe() []FieldInfo {
+	effective := Get().Masked()
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	cv := reflect.ValueOf(effective).Elem()
+	t := cv.Type()
+	var infos []FieldInfo
+	for i := 0; i < t.NumField(); i++ {
+		name := jsonName(t.Field(i))
+		if name == "" || name == "-" {
+			continue
+		}
+
+		info := FieldInfo{
+			Field:  name,
+			Value:  cv.Field(i).Interface(),
+			Source: SourceDefault,
+			Env:    EnvName(name),
+		}
+		if fileKeys[name] {
+			info.Source = SourceFile
+		}
+		if ov, ok := overlay[name]; ok {
+			info.Source = ov.source
+			info.Origin = ov.origin
+		}
+		if name == "schemaVersion" {
+			info.Env = ""
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
@@ -0,0 +1,146 @@
+package config
+
+import "testing"
+
+// sourceOf ritorna la provenienza di un campo secondo Describe
+func sourceOf(t *testing.T, field string) string {
+	t.Helper()
+
+	for _, info := range Describe() {
+		if info.Field == field {
+			return info.Source
+		}
+	}
+	t.Fatalf("field %s not described", field)
+	return ""
+}
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"serverUrl":     "SPOTLIVE_SERVER_URL",
+		"ftpPort":       "SPOTLIVE_FTP_PORT",
+		"mediaPrefixes": "SPOTLIVE_MEDIA_PREFIXES",
+		"idMonitor":     "SPOTLIVE_ID_MONITOR",
+	}
+	for field, want := range tests {
+		if got := EnvName(field); got != want {
+			t.Errorf("EnvName(%s) = %s, want %s", field, got, want)
+		}
+	}
+}
+
+func TestOverlayPrecedence(t *testing.T) {
+	SetDataDir(t.TempDir())
+	t.Cleanup(func() { SetOverlay(nil, nil) })
+
+	// Nel file: username e delay; ftpPort resta al default
+	writeTestConfig(t, map[string]interface{}{
+		"schemaVersion": SchemaVersion,
+		"username":      "file-user",
+		"delay":         1000,
+		"userSchermo":   "file-screen",
+	})
+	if _, err := Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	environ := []string{
+		"SPOTLIVE_USERNAME=env-user",
+		"SPOTLIVE_DELAY=2000",
+		"SPOTLIVE_MEDIA_PREFIXES=upload/, spot/",
+		"OTHER_VAR=ignored",
+	}
+	flags := map[string]string{"delay": "3000"}
+	if err := SetOverlay(environ, flags); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Get()
+	def := GetDefault()
+	tests := []struct {
+		field  string
+		got    interface{}
+		want   interface{}
+		source string
+	}{
+		{"ftpPort", cfg.FTPPort, def.FTPPort, SourceDefault},
+		{"userSchermo", cfg.UserSchermo, "file-screen", SourceFile},
+		{"username", cfg.Username, "env-user", SourceEnv},
+		{"delay", cfg.Delay, 3000, SourceFlag},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.field, tt.got, tt.want)
+		}
+		if src := sourceOf(t, tt.field); src != tt.source {
+			t.Errorf("%s source = %s, want %s", tt.field, src, tt.source)
+		}
+	}
+	if len(cfg.MediaPrefixes) != 2 || cfg.MediaPrefixes[1] != "spot/" {
+		t.Errorf("mediaPrefixes = %v", cfg.MediaPrefixes)
+	}
+	if origin, ok := OverriddenBy("username"); !ok || origin != "SPOTLIVE_USERNAME" {
+		t.Errorf("OverriddenBy(username) = %q, %v", origin, ok)
+	}
+
+	// Rimosso l'overlay torna il valore del file
+	if err := SetOverlay(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if cfg := Get(); cfg.Username != "file-user" || cfg.Delay != 1000 {
+		t.Errorf("without overlay: username %q, delay %d", cfg.Username, cfg.Delay)
+	}
+}
+
+func TestSetOverlayRejectsInvalidValues(t *testing.T) {
+	t.Cleanup(func() { SetOverlay(nil, nil) })
+
+	if err := SetOverlay([]string{"SPOTLIVE_FTP_PORT=abc"}, nil); err == nil {
+		t.Error("invalid integer from env accepted")
+	}
+	if err := SetOverlay(nil, map[string]string{"noSuchField": "x"}); err == nil {
+		t.Error("unknown -set field accepted")
+	}
+	if err := SetOverlay(nil, map[string]string{"schemaVersion": "9"}); err == nil {
+		t.Error("schemaVersion overlay accepted")
+	}
+}
+
+func TestSaveDoesNotPersistOverlay(t *testing.T) {
+	SetDataDir(t.TempDir())
+	t.Cleanup(func() { SetOverlay(nil, nil) })
+
+	cfg := GetDefault()
+	cfg.Username = "file-user"
+	cfg.Password = "file-secret"
+	if err := Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetOverlay([]string{"SPOTLIVE_PASSWORD=env-secret"}, map[string]string{"username": "flag-user"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Salvataggio della config effettiva con un campo non sovrapposto modificato
+	cfg = Get()
+	cfg.UserSchermo = "screen"
+	if err := Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	onDisk, _, err := loadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if onDisk.Username != "file-user" || onDisk.Password != "file-secret" {
+		t.Errorf("overlay persisted: username %q, password %q", onDisk.Username, onDisk.Password)
+	}
+	if onDisk.UserSchermo != "screen" {
+		t.Errorf("userSchermo = %q, want screen", onDisk.UserSchermo)
+	}
+
+	// La config effettiva mantiene i valori sovrapposti
+	if cfg := Get(); cfg.Username != "flag-user" || cfg.Password != "env-secret" {
+		t.Errorf("effective config lost overlay: %q %q", cfg.Username, cfg.Password)
+	}
+}
@@ -0,0 +1,235 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"reflect"
+	"spotlive-server/internal/apperr"
+	"strings"
+)
+
+// SecretMask valore restituito al posto dei segreti (ignorato in scrittura)
+const SecretMask = "***"
+
+// Patch aggiornamento parziale della configurazione: solo i campi presenti
+// (non nil) vengono applicati. I nomi dei campi coincidono con Config.
+type Patch struct {
+	ServerURL *string `json:"serverUrl,omitempty"`
+
+	ServletPath     *string `json:"servletPath,omitempty"`
+	ProtocolVersion *int    `json:"protocolVersion,omitempty"`
+	AuthMode        *string `json:"authMode,omitempty"`
+
+	HTTPConnectTimeout *int    `json:"httpConnectTimeout,omitempty"`
+	HTTPReadTimeout    *int    `json:"httpReadTimeout,omitempty"`
+	HTTPProxy          *string `json:"httpProxy,omitempty"`
+	CABundle           *string `json:"caBundle,omitempty"`
+	MaxScheduleBytes   *int    `json:"maxScheduleBytes,omitempty"`
+
+	Username    *string `json:"username,omitempty"`
+	Password    *string `json:"password,omitempty"`
+	IDMonitor   *string `json:"idMonitor,omitempty"`
+	UserSchermo *string `json:"userSchermo,omitempty"`
+
+	FTPServer    *string `json:"ftpServer,omitempty"`
+	FTPPort      *int    `json:"ftpPort,omitempty"`
+	FTPUsername  *string `json:"ftpUsername,omitempty"`
+	FTPPassword  *string `json:"ftpPassword,omitempty"`
+	FTPDirectory *string `json:"ftpDirectory,omitempty"`
+
+	ConnectionMode    *int `json:"connectionMode,omitempty"`
+	VideoQuality      *int `json:"videoQuality,omitempty"`
+	Delay             *int `json:"delay,omitempty"`
+	SecondiCache      *int `json:"secondiCache,omitempty"`
+	SecondiTolleranza *int `json:"secondiTolleranza,omitempty"`
+
+	DataDir *string `json:"dataDir,omitempty"`
+
+	MediaPrefixes *[]string `json:"mediaPrefixes,omitempty"`
+}
+
+// secretFields campi il cui valore non viene mai restituito dalle API
+var secretFields = map[string]bool{
+	"Password":    true,
+	"FTPPassword": true,
+}
+
+// urlSecretFields URL che possono contenere credenziali (user:pass@host):
+// la password è mascherata, il resto resta leggibile
+var urlSecretFields = map[string]bool{
+	"HTTPProxy": true,
+}
+
+// FieldErrors errori di validazione per campo (chiave = nome JSON)
+type FieldErrors map[string]string
+
+// DecodePatch decodifica una patch JSON. Campi sconosciuti o di tipo errato
+// sono rifiutati (un nome sbagliato non deve essere ignorato in silenzio).
+func DecodePatch(r io.Reader) (*Patch, error) {
+	d := json.NewDecoder(r)
+	d.DisallowUnknownFields()
+
+	var p Patch
+	err := d.Decode(&p)
+	if err == nil {
+		return &p, nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return nil, apperr.New(CodeInvalidFields, "Invalid configuration fields").
+			WithDetail("fields", FieldErrors{typeErr.Field: "must be " + typeErr.Type.String()})
+	}
+	// encoding/json non esporta un tipo per i campi sconosciuti
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return nil, apperr.New(CodeInvalidFields, "Invalid configuration fields").
+			WithDetail("fields", FieldErrors{strings.Trim(field, `"`): "unknown field"})
+	}
+	return nil, apperr.Wrap(err, apperr.CodeInvalidRequest, "Invalid request")
+}
+
+// Validate controlla che la patch non svuoti campi obbligatori. Formato e
+// range dei valori sono verificati da Config.Validate dopo Apply.
+func (p *Patch) Validate() FieldErrors {
+	errs := FieldErrors{}
+
+	required := func(field string, v *string) {
+		if v != nil && strings.TrimSpace(*v) == "" {
+			errs[field] = "must not be empty"
+		}
+	}
+
+	required("serverUrl", p.ServerURL)
+	required("servletPath", p.ServletPath)
+	required("authMode", p.AuthMode)
+	required("username", p.Username)
+	required("password", p.Password)
+	required("idMonitor", p.IDMonitor)
+	required("dataDir", p.DataDir)
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Apply applica la patch a cfg e ritorna i nomi JSON dei campi modificati.
+// I segreti uguali a SecretMask vengono ignorati (valore non modificato).
+func (p *Patch) Apply(cfg *Config) []string {
+	var changed []string
+
+	pv := reflect.ValueOf(p).Elem()
+	cv := reflect.ValueOf(cfg).Elem()
+	pt := pv.Type()
+
+	for i := 0; i < pt.NumField(); i++ {
+		field := pv.Field(i)
+		if field.IsNil() {
+			continue
+		}
+
+		name := pt.Field(i).Name
+		value := field.Elem()
+		if s, ok := value.Interface().(string); ok {
+			if secretFields[name] {
+				if s == SecretMask {
+					continue
+				}
+			} else {
+				value = reflect.ValueOf(strings.TrimSpace(s))
+			}
+			// URL rimandato così come restituito da Masked: invariato
+			if urlSecretFields[name] {
+				current := cv.FieldByName(name).String()
+				if s != current && s == maskURL(current, false) {
+					continue
+				}
+			}
+		}
+
+		target := cv.FieldByName(name)
+		if reflect.DeepEqual(target.Interface(), value.Interface()) {
+			continue
+		}
+		target.Set(value)
+		changed = append(changed, jsonName(pt.Field(i)))
+	}
+
+	return changed
+}
+
+// credentialFields campi che identificano l'infrastruttura del cliente:
+// visibili solo agli admin
+var credentialFields = map[string]bool{
+	"FTPServer":   true,
+	"FTPUsername": true,
+}
+
+// Public ritorna copia per client non admin: segreti e credenziali mascherati
+func (c *Config) Public() *Config {
+	cp := c.Masked()
+	cv := reflect.ValueOf(cp).Elem()
+	for name := range credentialFields {
+		if f := cv.FieldByName(name); f.String() != "" {
+			f.SetString(SecretMask)
+		}
+	}
+	for name := range urlSecretFields {
+		f := cv.FieldByName(name)
+		f.SetString(maskURL(c.fieldString(name), true))
+	}
+	return cp
+}
+
+// Masked ritorna copia con i segreti sostituiti da SecretMask
+func (c *Config) Masked() *Config {
+	cp := c.Clone()
+	cv := reflect.ValueOf(cp).Elem()
+	for name := range secretFields {
+		if f := cv.FieldByName(name); f.String() != "" {
+			f.SetString(SecretMask)
+		}
+	}
+	for name := range urlSecretFields {
+		f := cv.FieldByName(name)
+		f.SetString(maskURL(f.String(), false))
+	}
+	return cp
+}
+
+// fieldString valore stringa del campo name
+func (c *Config) fieldString(name string) string {
+	return reflect.ValueOf(c).Elem().FieldByName(name).String()
+}
+
+// maskURL sostituisce la password nelle credenziali dell'URL con
+// SecretMask (anche l'utente se user=true). URL non interpretabili con
+// credenziali sono mascherati per intero.
+func maskURL(raw string, user bool) string {
+	if !strings.Contains(raw, "@") {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return SecretMask
+	}
+	if u.User == nil {
+		return raw
+	}
+	name := u.User.Username()
+	if user {
+		name = SecretMask
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(name, SecretMask)
+	} else {
+		u.User = url.User(name)
+	}
+	return u.String()
+}
+
+func jsonName(f reflect.StructField) string {
+	return strings.Split(f.Tag.Get("json"), ",")[0]
+}
@@ -0,0 +1,338 @@
+package config
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"spotlive-server/internal/apperr"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Bundle di provisioning: configurazione comune a una flotta di box
+// (server, servlet, client HTTP, FTP, template ID monitor, tuning) cifrata con AES-256-GCM e
+// autenticata con HMAC-SHA256. Entrambe le chiavi derivano da una
+// passphrase condivisa (PBKDF2-SHA256), non dalla chiave del dispositivo:
+// lo stesso bundle si importa su qualsiasi box che conosca la passphrase.
+// Non è una firma: chi conosce la passphrase può anche creare bundle.
+const (
+	bundleFormat  = "slprov"
+	bundleVersion = 1
+
+	// bundleIterations iterazioni PBKDF2 per i nuovi bundle
+	bundleIterations = 200000
+	// Limiti accettati in import (evita bundle che bloccano la CPU)
+	minBundleIterations = 10000
+	maxBundleIterations = 5000000
+
+	// MinPassphraseLength lunghezza minima passphrase di provisioning
+	MinPassphraseLength = 8
+
+	// SerialPlaceholder sostituito con il seriale nel template ID monitor
+	SerialPlaceholder = "{serial}"
+)
+
+// Bundle contenuto (in chiaro) di un bundle di provisioning
+type Bundle struct {
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Server
+	ServerURL   string `json:"serverUrl"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	UserSchermo string `json:"userSchermo"`
+
+	// Servlet e client HTTP (assenti nei bundle precedenti)
+	ServletPath        string `json:"servletPath,omitempty"`
+	ProtocolVersion    int    `json:"protocolVersion,omitempty"`
+	AuthMode           string `json:"authMode,omitempty"`
+	HTTPConnectTimeout int    `json:"httpConnectTimeout,omitempty"`
+	HTTPReadTimeout    int    `json:"httpReadTimeout,omitempty"`
+	HTTPProxy          string `json:"httpProxy,omitempty"`
+	CABundle           string `json:"caBundle,omitempty"`
+	MaxScheduleBytes   int    `json:"maxScheduleBytes,omitempty"`
+
+	// FTP
+	FTPServer    string `json:"ftpServer"`
+	FTPPort      int    `json:"ftpPort"`
+	FTPUsername  string `json:"ftpUsername"`
+	FTPPassword  string `json:"ftpPassword"`
+	FTPDirectory string `json:"ftpDirectory"`
+
+	// ID monitor: prima la lista per seriale, poi il template
+	IDMonitorTemplate string            `json:"idMonitorTemplate,omitempty"` // es. "{serial}"
+	Monitors          map[string]string `json:"monitors,omitempty"`          // seriale → ID monitor
+
+	// Tuning
+	ConnectionMode    int      `json:"connectionMode"`
+	VideoQuality      int      `json:"videoQuality"`
+	Delay             int      `json:"delay"`
+	SecondiCache      int      `json:"secondiCache"`
+	SecondiTolleranza int      `json:"secondiTolleranza"`
+	MediaPrefixes     []string `json:"mediaPrefixes"`
+}
+
+// bundleEnvelope formato su file del bundle (JSON, campi binari in base64)
+type bundleEnvelope struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	Iterations int    `json:"iterations"`
+	Salt       string `json:"salt"`
+	Nonce      string `json:"nonce"`
+	Data       string `json:"data"`
+	MAC        string `json:"mac"`
+}
+
+var (
+	serialMu     sync.RWMutex
+	deviceSerial string
+)
+
+// SetDeviceSerial imposta seriale del dispositivo (usato per l'ID monitor)
+func SetDeviceSerial(serial string) {
+	serialMu.Lock()
+	deviceSerial = strings.TrimSpace(serial)
+	serialMu.Unlock()
+}
+
+// DeviceSerial ritorna seriale del dispositivo ("" se sconosciuto)
+func DeviceSerial() string {
+	serialMu.RLock()
+	defer serialMu.RUnlock()
+	return deviceSerial
+}
+
+// NewBundle crea bundle dalla configurazione cfg. L'ID monitor non viene
+// copiato: ogni box lo ricava da monitors o da template.
+func NewBundle(cfg *Config, template string, monitors map[string]string) *Bundle {
+	return &Bundle{
+		CreatedAt:          time.Now().UTC(),
+		ServerURL:          cfg.ServerURL,
+		Username:           cfg.Username,
+		Password:           cfg.Password,
+		UserSchermo:        cfg.UserSchermo,
+		ServletPath:        cfg.ServletPath,
+		ProtocolVersion:    cfg.ProtocolVersion,
+		AuthMode:           cfg.AuthMode,
+		HTTPConnectTimeout: cfg.HTTPConnectTimeout,
+		HTTPReadTimeout:    cfg.HTTPReadTimeout,
+		HTTPProxy:          cfg.HTTPProxy,
+		CABundle:           cfg.CABundle,
+		MaxScheduleBytes:   cfg.MaxScheduleBytes,
+		FTPServer:          cfg.FTPServer,
+		FTPPort:            cfg.FTPPort,
+		FTPUsername:        cfg.FTPUsername,
+		FTPPassword:        cfg.FTPPassword,
+		FTPDirectory:       cfg.FTPDirectory,
+		IDMonitorTemplate:  strings.TrimSpace(template),
+		Monitors:           monitors,
+		ConnectionMode:     cfg.ConnectionMode,
+		VideoQuality:       cfg.VideoQuality,
+		Delay:              cfg.Delay,
+		SecondiCache:       cfg.SecondiCache,
+		SecondiTolleranza:  cfg.SecondiTolleranza,
+		MediaPrefixes:      append([]string(nil), cfg.MediaPrefixes...),
+	}
+}
+
+// MonitorID ritorna ID monitor per il seriale indicato ("" se non ricavabile)
+func (b *Bundle) MonitorID(serial string) string {
+	if id, ok := b.Monitors[serial]; ok {
+		return strings.TrimSpace(id)
+	}
+	if b.IDMonitorTemplate == "" {
+		return ""
+	}
+	if strings.Contains(b.IDMonitorTemplate, SerialPlaceholder) {
+		if serial == "" {
+			return ""
+		}
+		return strings.ReplaceAll(b.IDMonitorTemplate, SerialPlaceholder, serial)
+	}
+	return b.IDMonitorTemplate
+}
+
+// Apply copia i valori del bundle su cfg. L'ID monitor viene impostato solo
+// se ricavabile dal seriale, altrimenti resta quello corrente; servlet e
+// client HTTP solo se presenti nel bundle (vuoto = valore corrente).
+func (b *Bundle) Apply(cfg *Config, serial string) {
+	cfg.ServerURL = b.ServerURL
+	cfg.Username = b.Username
+	cfg.Password = b.Password
+	cfg.UserSchermo = b.UserSchermo
+	if b.ServletPath != "" {
+		cfg.ServletPath = b.ServletPath
+	}
+	if b.ProtocolVersion != 0 {
+		cfg.ProtocolVersion = b.ProtocolVersion
+	}
+	if b.AuthMode != "" {
+		cfg.AuthMode = b.AuthMode
+	}
+	if b.HTTPConnectTimeout != 0 {
+		cfg.HTTPConnectTimeout = b.HTTPConnectTimeout
+	}
+	if b.HTTPReadTimeout != 0 {
+		cfg.HTTPReadTimeout = b.HTTPReadTimeout
+	}
+	if b.HTTPProxy != "" {
+		cfg.HTTPProxy = b.HTTPProxy
+	}
+	if b.CABundle != "" {
+		cfg.CABundle = b.CABundle
+	}
+	if b.MaxScheduleBytes != 0 {
+		cfg.MaxScheduleBytes = b.MaxScheduleBytes
+	}
+	cfg.FTPServer = b.FTPServer
+	cfg.FTPPort = b.FTPPort
+	cfg.FTPUsername = b.FTPUsername
+	cfg.FTPPassword = b.FTPPassword
+	cfg.FTPDirectory = b.FTPDirectory
+	cfg.ConnectionMode = b.ConnectionMode
+	cfg.VideoQuality = b.VideoQuality
+	cfg.Delay = b.Delay
+	cfg.SecondiCache = b.SecondiCache
+	cfg.SecondiTolleranza = b.SecondiTolleranza
+	if len(b.MediaPrefixes) > 0 {
+		cfg.MediaPrefixes = append([]string(nil), b.MediaPrefixes...)
+	}
+	if id := b.MonitorID(serial); id != "" {
+		cfg.IDMonitor = id
+	}
+}
+
+// SealBundle cifra e autentica il bundle con la passphrase
+func SealBundle(b *Bundle, passphrase string) ([]byte, error) {
+	if len(passphrase) < MinPassphraseLength {
+		return nil, apperr.New(CodeInvalidFields, "passphrase too short").
+			WithDetail("fields", FieldErrors{"passphrase": fmt.Sprintf("must be at least %d characters", MinPassphraseLength)})
+	}
+
+	plaintext, err := json.Marshal(b)
+	if err != nil {
+		return nil, apperr.Wrap(err, apperr.CodeInternal, "bundle encode failed")
+	}
+
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, apperr.Wrap(err, apperr.CodeInternal, "bundle encrypt failed")
+	}
+	encKey, macKey := bundleKeys(passphrase, salt, bundleIterations)
+
+	gcm, err := newGCM(encKey)
+	if err != nil {
+		return nil, apperr.Wrap(err, apperr.CodeInternal, "bundle encrypt failed")
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, apperr.Wrap(err, apperr.CodeInternal, "bundle encrypt failed")
+	}
+
+	env := bundleEnvelope{
+		Format:     bundleFormat,
+		Version:    bundleVersion,
+		Iterations: bundleIterations,
+		Salt:       base64.StdEncoding.EncodeToString(salt),
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Data:       base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, plaintext, []byte(bundleFormat))),
+	}
+	env.MAC = base64.StdEncoding.EncodeToString(env.mac(macKey))
+
+	return json.MarshalIndent(env, "", "  ")
+}
+
+// OpenBundle verifica autenticità e decifra il bundle con la passphrase
+func OpenBundle(data []byte, passphrase string) (*Bundle, error) {
+	var env bundleEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, apperr.Wrap(err, CodeBundleInvalid, "provisioning bundle is not valid JSON")
+	}
+	if env.Format != bundleFormat || env.Version != bundleVersion {
+		return nil, apperr.New(CodeBundleInvalid, "unsupported provisioning bundle").
+			WithDetail("format", env.Format).
+			WithDetail("version", env.Version)
+	}
+	if env.Iterations < minBundleIterations || env.Iterations > maxBundleIterations {
+		return nil, apperr.New(CodeBundleInvalid, "invalid provisioning bundle parameters").
+			WithDetail("iterations", env.Iterations)
+	}
+
+	salt, err1 := base64.StdEncoding.DecodeString(env.Salt)
+	nonce, err2 := base64.StdEncoding.DecodeString(env.Nonce)
+	ciphertext, err3 := base64.StdEncoding.DecodeString(env.Data)
+	mac, err4 := base64.StdEncoding.DecodeString(env.MAC)
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil || len(salt) == 0 {
+		return nil, apperr.New(CodeBundleInvalid, "provisioning bundle corrupted")
+	}
+
+	// MAC verificato prima di decifrare: passphrase errata o bundle alterato
+	encKey, macKey := bundleKeys(passphrase, salt, env.Iterations)
+	if !hmac.Equal(mac, env.mac(macKey)) {
+		return nil, apperr.New(CodeBundleAuth, "provisioning bundle authentication failed (wrong passphrase or modified bundle)")
+	}
+
+	gcm, err := newGCM(encKey)
+	if err != nil {
+		return nil, apperr.Wrap(err, apperr.CodeInternal, "bundle decrypt failed")
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, apperr.New(CodeBundleInvalid, "provisioning bundle corrupted")
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(bundleFormat))
+	if err != nil {
+		return nil, apperr.Wrap(err, CodeBundleAuth, "provisioning bundle authentication failed")
+	}
+
+	var b Bundle
+	if err := json.Unmarshal(plaintext, &b); err != nil {
+		return nil, apperr.Wrap(err, CodeBundleInvalid, "provisioning bundle content invalid")
+	}
+	return &b, nil
+}
+
+// ApplyProvisionFile applica il bundle in path alla configurazione corrente
+// e la salva. Usato al primo avvio con -provision.
+func ApplyProvisionFile(path, passphrase string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, apperr.Wrap(err, CodeBundleInvalid, "cannot read provisioning bundle").
+			WithDetail("path", path)
+	}
+	b, err := OpenBundle(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := Get()
+	b.Apply(cfg, DeviceSerial())
+	if errs := cfg.Validate(); errs != nil {
+		return nil, apperr.New(CodeInvalidFields, "provisioning bundle has invalid fields").
+			WithDetail("fields", errs)
+	}
+	if err := Save(cfg); err != nil {
+		return nil, err
+	}
+	return Get(), nil
+}
+
+// mac calcola HMAC-SHA256 su tutti i campi dell'envelope tranne il MAC
+func (e *bundleEnvelope) mac(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s:%d:%d:%s:%s:%s", e.Format, e.Version, e.Iterations, e.Salt, e.Nonce, e.Data)
+	return mac.Sum(nil)
+}
+
+// bundleKeys deriva chiave di cifratura e chiave del MAC dalla passphrase
+func bundleKeys(passphrase string, salt []byte, iterations int) (encKey, macKey []byte) {
+	key := pbkdf2.Key([]byte(passphrase), salt, iterations, 64, sha256.New)
+	return key[:32], key[32:]
+}
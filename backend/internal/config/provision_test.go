@@ -0,0 +1,210 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"spotlive-server/internal/apperr"
+	"testing"
+)
+
+// sealTestBundle crea bundle cifrato da una config di prova
+func sealTestBundle(t *testing.T, passphrase string) []byte {
+	t.Helper()
+
+	cfg := GetDefault()
+	cfg.ServerURL = "http://cms.example:8080"
+	cfg.Username = "user"
+	cfg.Password = "secret"
+	cfg.FTPServer = "ftp.example"
+	cfg.FTPPassword = "ftp-secret"
+
+	data, err := SealBundle(NewBundle(cfg, "box-{serial}", map[string]string{"SN1": "567"}), passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// tamperBundle modifica un campo dell'envelope
+func tamperBundle(t *testing.T, data []byte, edit func(*bundleEnvelope)) []byte {
+	t.Helper()
+
+	var env bundleEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		t.Fatal(err)
+	}
+	edit(&env)
+	out, err := json.Marshal(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// flipBase64 cambia un byte di un campo base64
+func flipBase64(t *testing.T, field string) string {
+	t.Helper()
+
+	raw, err := base64.StdEncoding.DecodeString(field)
+	if err != nil || len(raw) == 0 {
+		t.Fatalf("invalid field %q", field)
+	}
+	raw[len(raw)-1] ^= 0x01
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	data := sealTestBundle(t, "fleet-passphrase")
+
+	b, err := OpenBundle(data, "fleet-passphrase")
+	if err != nil {
+		t.Fatalf("OpenBundle: %v", err)
+	}
+	if b.ServerURL != "http://cms.example:8080" || b.Password != "secret" || b.FTPPassword != "ftp-secret" {
+		t.Errorf("bundle = %+v", b)
+	}
+
+	cfg := GetDefault()
+	b.Apply(cfg, "SN1")
+	if cfg.ServerURL != b.ServerURL || cfg.FTPServer != "ftp.example" || cfg.IDMonitor != "567" {
+		t.Errorf("applied config = %+v", cfg)
+	}
+}
+
+func TestSealBundleShortPassphrase(t *testing.T) {
+	_, err := SealBundle(NewBundle(GetDefault(), "", nil), "short")
+	if apperr.CodeOf(err) != CodeInvalidFields {
+		t.Errorf("SealBundle = %v, want %s", err, CodeInvalidFields)
+	}
+}
+
+func TestOpenBundleWrongPassphrase(t *testing.T) {
+	data := sealTestBundle(t, "fleet-passphrase")
+
+	if _, err := OpenBundle(data, "other-passphrase"); apperr.CodeOf(err) != CodeBundleAuth {
+		t.Errorf("OpenBundle = %v, want %s", err, CodeBundleAuth)
+	}
+}
+
+func TestOpenBundleTampered(t *testing.T) {
+	data := sealTestBundle(t, "fleet-passphrase")
+
+	tests := []struct {
+		name string
+		edit func(*bundleEnvelope)
+	}{
+		{"iterations", func(e *bundleEnvelope) { e.Iterations++ }},
+		{"salt", func(e *bundleEnvelope) { e.Salt = flipBase64(t, e.Salt) }},
+		{"nonce", func(e *bundleEnvelope) { e.Nonce = flipBase64(t, e.Nonce) }},
+		{"data", func(e *bundleEnvelope) { e.Data = flipBase64(t, e.Data) }},
+		{"mac", func(e *bundleEnvelope) { e.MAC = flipBase64(t, e.MAC) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := OpenBundle(tamperBundle(t, data, tt.edit), "fleet-passphrase")
+			if apperr.CodeOf(err) != CodeBundleAuth {
+				t.Errorf("OpenBundle = %v, want %s", err, CodeBundleAuth)
+			}
+		})
+	}
+}
+
+func TestOpenBundleIterationBounds(t *testing.T) {
+	data := sealTestBundle(t, "fleet-passphrase")
+
+	for _, n := range []int{0, minBundleIterations - 1, maxBundleIterations + 1} {
+		tampered := tamperBundle(t, data, func(e *bundleEnvelope) { e.Iterations = n })
+		_, err := OpenBundle(tampered, "fleet-passphrase")
+		if apperr.CodeOf(err) != CodeBundleInvalid {
+			t.Errorf("iterations %d: OpenBundle = %v, want %s", n, err, CodeBundleInvalid)
+		}
+	}
+}
+
+func TestOpenBundleInvalidEnvelope(t *testing.T) {
+	data := sealTestBundle(t, "fleet-passphrase")
+
+	tests := map[string][]byte{
+		"not json": []byte("not a bundle"),
+		"format":   tamperBundle(t, data, func(e *bundleEnvelope) { e.Format = "other" }),
+		"version":  tamperBundle(t, data, func(e *bundleEnvelope) { e.Version = bundleVersion + 1 }),
+		"base64":   tamperBundle(t, data, func(e *bundleEnvelope) { e.Salt = "%%%" }),
+	}
+	for name, in := range tests {
+		if _, err := OpenBundle(in, "fleet-passphrase"); apperr.CodeOf(err) != CodeBundleInvalid {
+			t.Errorf("%s: OpenBundle = %v, want %s", name, err, CodeBundleInvalid)
+		}
+	}
+}
+
+func TestBundleMonitorID(t *testing.T) {
+	b := &Bundle{
+		IDMonitorTemplate: "box-{serial}",
+		Monitors:          map[string]string{"SN1": " 567 "},
+	}
+
+	tests := map[string]string{
+		"SN1": "567",     // lista per seriale
+		"SN2": "box-SN2", // template
+		"":    "",        // template con seriale sconosciuto
+	}
+	for serial, want := range tests {
+		if got := b.MonitorID(serial); got != want {
+			t.Errorf("MonitorID(%q) = %q, want %q", serial, got, want)
+		}
+	}
+
+	// Template fisso: uguale per tutti i box
+	fixed := &Bundle{IDMonitorTemplate: "999"}
+	if got := fixed.MonitorID(""); got != "999" {
+		t.Errorf("fixed template MonitorID = %q", got)
+	}
+
+	// Nessun ID ricavabile: Apply mantiene quello corrente
+	cfg := GetDefault()
+	cfg.IDMonitor = "123"
+	(&Bundle{}).Apply(cfg, "SN3")
+	if cfg.IDMonitor != "123" {
+		t.Errorf("IDMonitor = %q, want unchanged", cfg.IDMonitor)
+	}
+}
+
+func TestBundleCarriesServletAndHTTPSettings(t *testing.T) {
+	src := GetDefault()
+	src.ServletPath = "/cms/XmlServlet"
+	src.ProtocolVersion = 700
+	src.AuthMode = "basic"
+	src.HTTPConnectTimeout = 5
+	src.HTTPReadTimeout = 60
+	src.HTTPProxy = "http://proxy.example:3128"
+	src.CABundle = "-----BEGIN CERTIFICATE-----"
+	src.MaxScheduleBytes = 16 << 20
+
+	data, err := SealBundle(NewBundle(src, "", nil), "fleet-passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := OpenBundle(data, "fleet-passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := GetDefault()
+	b.Apply(cfg, "")
+	if cfg.ServletPath != src.ServletPath || cfg.ProtocolVersion != src.ProtocolVersion || cfg.AuthMode != src.AuthMode {
+		t.Errorf("servlet settings = %q %d %q", cfg.ServletPath, cfg.ProtocolVersion, cfg.AuthMode)
+	}
+	if cfg.HTTPConnectTimeout != 5 || cfg.HTTPReadTimeout != 60 || cfg.HTTPProxy != src.HTTPProxy ||
+		cfg.CABundle != src.CABundle || cfg.MaxScheduleBytes != src.MaxScheduleBytes {
+		t.Errorf("http settings = %+v", cfg)
+	}
+
+	// Bundle precedente senza questi campi: valori correnti invariati
+	current := GetDefault()
+	current.AuthMode = "token"
+	current.HTTPReadTimeout = 45
+	(&Bundle{ServerURL: "http://cms.example"}).Apply(current, "")
+	if current.AuthMode != "token" || current.HTTPReadTimeout != 45 || current.ServletPath != GetDefault().ServletPath {
+		t.Errorf("old bundle overwrote settings: %+v", current)
+	}
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+var (
+	// fileMu serializza Load/Save/Reload (accesso al file e a futureVersion)
+	fileMu sync.Mutex
+
+	// mu protegge snapshot, overlay e sottoscrittori
+	mu          sync.RWMutex
+	current     *Config         // configurazione effettiva (file + overlay)
+	persisted   *Config         // configurazione come salvata su file
+	fileKeys    map[string]bool // campi presenti nel file
+	subscribers = make(map[int]chan *Config)
+	nextSubID   int
+
+	// holdMu protegge la sospensione di Reload (reset in corso)
+	holdMu        sync.Mutex
+	reloadHolds   int
+	reloadPending bool
+)
+
+// Clone ritorna copia profonda della configurazione
+func (c *Config) Clone() *Config {
+	cp := *c
+	if c.MediaPrefixes != nil {
+		cp.MediaPrefixes = append([]string(nil), c.MediaPrefixes...)
+	}
+	return &cp
+}
+
+// Get ritorna snapshot della configurazione corrente. Lo snapshot è una
+// copia: modificarlo non ha effetto, usare Save per rendere persistente.
+func Get() *Config {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if current == nil {
+		return GetDefault()
+	}
+	return current.Clone()
+}
+
+// persistedSnapshot ritorna copia della config persistita (nil se mai caricata)
+func persistedSnapshot() *Config {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if persisted == nil {
+		return nil
+	}
+	return persisted.Clone()
+}
+
+// publish sostituisce la config persistita, ricalcola quella effettiva con
+// gli overlay e notifica i sottoscrittori se cambiata
+func publish(cfg *Config) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	persisted = cfg.Clone()
+	snapshot := cfg.Clone()
+	applyOverlay(snapshot)
+
+	changed := current == nil || !reflect.DeepEqual(current, snapshot)
+	current = snapshot
+	if !changed {
+		return
+	}
+
+	for _, ch := range subscribers {
+		// Conserva solo l'ultimo snapshot se il sottoscrittore è in ritardo
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- snapshot.Clone()
+	}
+}
+
+// Subscribe ritorna canale che riceve ogni nuova configurazione (solo
+// l'ultima se il lettore è lento) e funzione per annullare la sottoscrizione
+func Subscribe() (<-chan *Config, func()) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	id := nextSubID
+	nextSubID++
+	ch := make(chan *Config, 1)
+	subscribers[id] = ch
+
+	return ch, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, ok := subscribers[id]; ok {
+			delete(subscribers, id)
+			close(ch)
+		}
+	}
+}
+
+// Reload rilegge configurazione da disco e notifica se cambiata. Con
+// HoldReload attivo la rilettura è rimandata alla ripresa.
+func Reload() error {
+	holdMu.Lock()
+	if reloadHolds > 0 {
+		reloadPending = true
+		holdMu.Unlock()
+		return nil
+	}
+	holdMu.Unlock()
+
+	_, err := Load()
+	return err
+}
+
+// HoldReload sospende Reload (file watcher, SIGHUP) mentre i file vengono
+// cancellati o riscritti; la funzione ritornata riprende ed esegue una
+// rilettura richiesta nel frattempo
+func HoldReload() (release func()) {
+	holdMu.Lock()
+	reloadHolds++
+	holdMu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			holdMu.Lock()
+			reloadHolds--
+			pending := reloadHolds == 0 && reloadPending
+			if pending {
+				reloadPending = false
+			}
+			holdMu.Unlock()
+
+			if pending {
+				if err := Reload(); err != nil {
+					log.Printf("Warning: config reload failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// WatchFile ricarica la config quando il file cambia (polling su mtime e
+// dimensione, nessuna dipendenza da inotify) fino alla chiusura di stop
+func WatchFile(interval time.Duration, stop <-chan struct{}) {
+	stamp := func() (time.Time, int64) {
+		info, err := os.Stat(configPath)
+		if err != nil {
+			return time.Time{}, -1
+		}
+		return info.ModTime(), info.Size()
+	}
+
+	lastMod, lastSize := stamp()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			mod, size := stamp()
+			if mod.Equal(lastMod) && size == lastSize {
+				continue
+			}
+			lastMod, lastSize = mod, size
+
+			if err := Reload(); err != nil {
+				log.Printf("Warning: config reload failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitConfig attende la prossima config pubblicata
+func waitConfig(t *testing.T, ch <-chan *Config) *Config {
+	t.Helper()
+
+	select {
+	case cfg := <-ch:
+		return cfg
+	case <-time.After(2 * time.Second):
+		t.Fatal("no config notification")
+		return nil
+	}
+}
+
+func TestGetReturnsIsolatedSnapshot(t *testing.T) {
+	SetDataDir(t.TempDir())
+	cfg := GetDefault()
+	cfg.Username = "user"
+	cfg.MediaPrefixes = []string{"upload/"}
+	if err := Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	snap := Get()
+	snap.Username = "changed"
+	snap.MediaPrefixes[0] = "changed/"
+	// Anche la config passata a Save resta del chiamante
+	cfg.Username = "changed-after-save"
+
+	got := Get()
+	if got.Username != "user" || got.MediaPrefixes[0] != "upload/" {
+		t.Errorf("snapshot modification leaked: %q %v", got.Username, got.MediaPrefixes)
+	}
+}
+
+func TestSubscribeNotifiesChanges(t *testing.T) {
+	SetDataDir(t.TempDir())
+	if _, err := Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	ch, cancel := Subscribe()
+	defer cancel()
+
+	cfg := GetDefault()
+	cfg.Username = "first"
+	if err := Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+	got := waitConfig(t, ch)
+	if got.Username != "first" {
+		t.Errorf("notified username = %q", got.Username)
+	}
+
+	// Snapshot ricevuto indipendente da quello corrente
+	got.Username = "modified"
+	if Get().Username != "first" {
+		t.Error("notified snapshot shares state with current config")
+	}
+
+	// Nessuna modifica: nessuna notifica
+	if err := Save(Get()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case cfg := <-ch:
+		t.Errorf("unexpected notification: %+v", cfg)
+	default:
+	}
+
+	// Lettore lento: riceve solo l'ultima config
+	for _, name := range []string{"a", "b", "c"} {
+		cfg := Get()
+		cfg.Username = name
+		if err := Save(cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := waitConfig(t, ch); got.Username != "c" {
+		t.Errorf("latest username = %q, want c", got.Username)
+	}
+
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Error("channel not closed after cancel")
+	}
+	cancel() // idempotente
+}
+
+func TestWatchFileReloadsOnChange(t *testing.T) {
+	SetDataDir(t.TempDir())
+	cfg := GetDefault()
+	cfg.Username = "before"
+	if err := Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	ch, cancel := Subscribe()
+	defer cancel()
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		WatchFile(10*time.Millisecond, stop)
+		close(done)
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	// Modifica esterna del file (es. altro processo o ripristino manuale)
+	time.Sleep(30 * time.Millisecond)
+	writeTestConfig(t, map[string]interface{}{
+		"schemaVersion": SchemaVersion,
+		"username":      "after-external-edit",
+	})
+
+	if got := waitConfig(t, ch); got.Username != "after-external-edit" {
+		t.Errorf("reloaded username = %q", got.Username)
+	}
+	if Get().Username != "after-external-edit" {
+		t.Errorf("current username = %q", Get().Username)
+	}
+}
+
+func TestConcurrentGetSaveSubscribe(t *testing.T) {
+	SetDataDir(t.TempDir())
+	if _, err := Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				cfg := Get()
+				cfg.Delay = i*100 + j
+				if err := Save(cfg); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				cfg := Get()
+				cfg.MediaPrefixes = append(cfg.MediaPrefixes, "x/")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			ch, cancel := Subscribe()
+			for j := 0; j < 5; j++ {
+				select {
+				case <-ch:
+				case <-time.After(10 * time.Millisecond):
+				}
+			}
+			cancel()
+		}()
+	}
+	wg.Wait()
+
+	if err := Reload(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHoldReloadDefersReload(t *testing.T) {
+	SetDataDir(t.TempDir())
+	cfg := GetDefault()
+	cfg.Username = "before"
+	if err := Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	release := HoldReload()
+	writeTestConfig(t, map[string]interface{}{
+		"schemaVersion": SchemaVersion,
+		"username":      "after",
+	})
+	if err := Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := Get().Username; got != "before" {
+		t.Errorf("reloaded while held: %q", got)
+	}
+
+	// Ripresa: la rilettura richiesta viene eseguita
+	release()
+	release() // idempotente
+	if got := Get().Username; got != "after" {
+		t.Errorf("username after release = %q, want after", got)
+	}
+}
@@ -0,0 +1,294 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Default del servlet programmazione (client Windows storico)
+const (
+	DefaultServletPath     = "/spotlivescreen/XmlServlet"
+	DefaultProtocolVersion = 600
+	MaxProtocolVersion     = 9999
+)
+
+// Modalità di autenticazione verso il servlet (Username/Password)
+const (
+	AuthAuto  = "auto"  // rilevata con la negoziazione, altrimenti none
+	AuthNone  = "none"  // solo idSchermo/userschermo (client storico)
+	AuthQuery = "query" // parametri username/password
+	AuthBasic = "basic" // HTTP Basic
+	AuthToken = "token" // Authorization: Bearer <password>
+)
+
+// AuthModes modalità di autenticazione accettate
+var AuthModes = []string{AuthAuto, AuthNone, AuthQuery, AuthBasic, AuthToken}
+
+// Timeout massimo (secondi) del client HTTP
+const MaxHTTPTimeout = 300
+
+// Limiti della dimensione della risposta programmazione (byte)
+const (
+	DefaultMaxScheduleBytes = 8 << 20
+	MinScheduleBytesLimit   = 64 << 10
+	MaxScheduleBytesLimit   = 256 << 20
+)
+
+// Limiti dei parametri numerici del player
+const (
+	MaxDelayMs           = 600000 // 10 minuti
+	MaxSecondiCache      = 3600
+	MaxSecondiTolleranza = 3600
+	MaxVideoQuality      = 100
+	MaxConnectionMode    = 3
+)
+
+// Validate verifica formato e range di tutti i campi. Ritorna nil se valida,
+// altrimenti un errore per campo (chiave = nome JSON) con indicazione della
+// correzione. Non richiede i campi di identità (vedi CheckConfigured).
+func (c *Config) Validate() FieldErrors {
+	errs := FieldErrors{}
+	set := func(field, msg string) {
+		if _, ok := errs[field]; !ok {
+			errs[field] = msg
+		}
+	}
+
+	if msg := validateServerURL(c.ServerURL); msg != "" {
+		set("serverUrl", msg)
+	}
+
+	if msg := validateServletPath(c.ServletPath); msg != "" {
+		set("servletPath", msg)
+	}
+	if c.ProtocolVersion < 1 || c.ProtocolVersion > MaxProtocolVersion {
+		set("protocolVersion", fmt.Sprintf("must be between 1 and %d (default %d)", MaxProtocolVersion, DefaultProtocolVersion))
+	}
+	if !validAuthMode(c.AuthMode) {
+		set("authMode", "must be one of: "+strings.Join(AuthModes, ", "))
+	}
+
+	if c.HTTPConnectTimeout < 1 || c.HTTPConnectTimeout > MaxHTTPTimeout {
+		set("httpConnectTimeout", fmt.Sprintf("must be between 1 and %d seconds", MaxHTTPTimeout))
+	}
+	if c.HTTPReadTimeout < 1 || c.HTTPReadTimeout > MaxHTTPTimeout {
+		set("httpReadTimeout", fmt.Sprintf("must be between 1 and %d seconds", MaxHTTPTimeout))
+	}
+	if c.HTTPProxy != "" {
+		if msg := validateProxyURL(c.HTTPProxy); msg != "" {
+			set("httpProxy", msg)
+		}
+	}
+	if c.CABundle != "" && !filepath.IsAbs(c.CABundle) {
+		set("caBundle", "must be an absolute path to a PEM file")
+	}
+	if c.MaxScheduleBytes < MinScheduleBytesLimit || c.MaxScheduleBytes > MaxScheduleBytesLimit {
+		set("maxScheduleBytes", fmt.Sprintf("must be between %d and %d bytes (default %d)",
+			MinScheduleBytesLimit, MaxScheduleBytesLimit, DefaultMaxScheduleBytes))
+	}
+
+	if c.IDMonitor != "" {
+		if _, err := strconv.Atoi(c.IDMonitor); err != nil {
+			set("idMonitor", "must be a number (e.g. 567)")
+		}
+	}
+
+	if c.FTPServer != "" {
+		if msg := validateHost(c.FTPServer); msg != "" {
+			set("ftpServer", msg)
+		}
+	}
+	if c.FTPPort < 1 || c.FTPPort > 65535 {
+		set("ftpPort", "must be between 1 and 65535")
+	}
+	if msg := validateFTPDirectory(c.FTPDirectory); msg != "" {
+		set("ftpDirectory", msg)
+	}
+
+	bound := func(field string, v, max int) {
+		if v < 0 || v > max {
+			set(field, fmt.Sprintf("must be between 0 and %d", max))
+		}
+	}
+	bound("connectionMode", c.ConnectionMode, MaxConnectionMode)
+	bound("videoQuality", c.VideoQuality, MaxVideoQuality)
+	bound("delay", c.Delay, MaxDelayMs)
+	bound("secondiCache", c.SecondiCache, MaxSecondiCache)
+	bound("secondiTolleranza", c.SecondiTolleranza, MaxSecondiTolleranza)
+
+	for _, prefix := range c.MediaPrefixes {
+		if strings.Trim(prefix, "/") == "" || strings.Contains(prefix, "..") || strings.Contains(prefix, "\\") {
+			set("mediaPrefixes", fmt.Sprintf("invalid prefix %q", prefix))
+		}
+	}
+
+	if c.DataDir != "" {
+		if msg := checkWritable(c.DataDir); msg != "" {
+			set("dataDir", msg)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// validateServerURL verifica URL del servlet (http[s]://host[:porta], senza path)
+func validateServerURL(raw string) string {
+	if raw == "" {
+		return "required"
+	}
+	if strings.TrimSpace(raw) != raw || strings.ContainsAny(raw, " \t") {
+		return "must not contain spaces"
+	}
+	if !strings.Contains(raw, "://") {
+		return "missing scheme: use http://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "invalid URL"
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Sprintf("unsupported scheme %q: use http:// or https://", u.Scheme)
+	}
+	if u.Host == "" || u.Hostname() == "" {
+		return "missing host"
+	}
+	if port := u.Port(); port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "port must be between 1 and 65535"
+		}
+	} else if strings.HasSuffix(u.Host, ":") {
+		return "empty port"
+	}
+	if u.Path != "" && u.Path != "/" {
+		return "must not contain a path (set it in servletPath)"
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return "must not contain query or fragment"
+	}
+	if u.User != nil {
+		return "must not contain credentials"
+	}
+	return ""
+}
+
+// validateProxyURL verifica proxy (http, https o socks5 con host)
+func validateProxyURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || !strings.Contains(raw, "://") {
+		return "must be a URL (e.g. http://proxy:3128)"
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return fmt.Sprintf("unsupported scheme %q: use http://, https:// or socks5://", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return "missing host"
+	}
+	return ""
+}
+
+// validateServletPath verifica path del servlet (assoluto, senza query)
+func validateServletPath(path string) string {
+	switch {
+	case path == "":
+		return "required (default " + DefaultServletPath + ")"
+	case !strings.HasPrefix(path, "/"):
+		return "must start with /"
+	case strings.ContainsAny(path, "?# \t"):
+		return "must not contain query, fragment or spaces"
+	case strings.Contains(path, ".."):
+		return "must not contain .."
+	}
+	return ""
+}
+
+func validAuthMode(mode string) bool {
+	for _, m := range AuthModes {
+		if mode == m {
+			return true
+		}
+	}
+	return false
+}
+
+// validateHost verifica host FTP (nome o IP, senza schema, porta o path)
+func validateHost(host string) string {
+	switch {
+	case strings.Contains(host, "://"):
+		return "must be a host name without scheme (e.g. ftp.example.com)"
+	case strings.ContainsAny(host, "/ \t"):
+		return "must be a host name without path or spaces"
+	case strings.Contains(host, ":") && net.ParseIP(host) == nil:
+		return "port goes in ftpPort"
+	}
+	return ""
+}
+
+// validateFTPDirectory verifica directory FTP assoluta e normalizzata
+func validateFTPDirectory(dir string) string {
+	switch {
+	case dir == "":
+		return "required (use / for the root)"
+	case !strings.HasPrefix(dir, "/"):
+		return "must start with /"
+	case strings.Contains(dir, "\\"):
+		return "must use / as separator"
+	case strings.Contains(dir, "//"):
+		return "must not contain empty segments"
+	}
+	for _, seg := range strings.Split(dir, "/") {
+		if seg == "." || seg == ".." {
+			return "must not contain . or .. segments"
+		}
+	}
+	return ""
+}
+
+// checkWritable verifica che la directory sia scrivibile o creabile senza
+// modificare il filesystem (Validate serve anche per le anteprime): se non
+// esiste si controlla il primo antenato esistente. La creazione spetta a Save.
+func checkWritable(dir string) string {
+	if !filepath.IsAbs(dir) {
+		return "must be an absolute path"
+	}
+
+	existing := filepath.Clean(dir)
+	for {
+		info, err := os.Stat(existing)
+		if err == nil {
+			if !info.IsDir() {
+				if existing == filepath.Clean(dir) {
+					return "not a directory"
+				}
+				return "cannot create directory"
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return "directory not accessible"
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return "cannot create directory"
+		}
+		existing = parent
+	}
+
+	if !dirWritable(existing) {
+		if existing != filepath.Clean(dir) {
+			return "cannot create directory"
+		}
+		return "directory not writable"
+	}
+	return ""
+}
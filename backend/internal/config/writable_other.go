@@ -0,0 +1,12 @@
+//go:build !unix
+
+package config
+
+import "os"
+
+// dirWritable verifica il permesso di scrittura senza creare file
+// (approssimato dai bit di permesso del proprietario)
+func dirWritable(dir string) bool {
+	info, err := os.Stat(dir)
+	return err == nil && info.Mode().Perm()&0200 != 0
+}
@@ -0,0 +1,13 @@
+//go:build unix
+
+package config
+
+import "syscall"
+
+// accessWrite permesso di scrittura per access(2) (W_OK)
+const accessWrite = 0x2
+
+// dirWritable verifica il permesso di scrittura senza creare file
+func dirWritable(dir string) bool {
+	return syscall.Access(dir, accessWrite) == nil
+}
@@ -0,0 +1,32 @@
+package ftp
+
+import (
+	"errors"
+	"net/textproto"
+	"spotlive-server/internal/apperr"
+)
+
+// Codici errore FTP
+const (
+	CodeUnreachable    apperr.Code = "FTP_UNREACHABLE"
+	CodeAuthFailed     apperr.Code = "FTP_AUTH_FAILED"
+	CodeDirNotFound    apperr.Code = "FTP_DIRECTORY_NOT_FOUND"
+	CodeFileNotFound   apperr.Code = "FTP_FILE_NOT_FOUND"
+	CodeTransferFailed apperr.Code = "FTP_TRANSFER_FAILED"
+	CodeNotConfigured  apperr.Code = "FTP_NOT_CONFIGURED"
+)
+
+// replyCode estrae codice risposta FTP (es. 550) se presente
+func replyCode(err error) int {
+	var tpErr *textproto.Error
+	if errors.As(err, &tpErr) {
+		return tpErr.Code
+	}
+	return 0
+}
+
+// isPermanent verifica se risposta FTP è un errore permanente (5xx)
+func isPermanent(err error) bool {
+	code := replyCode(err)
+	return code >= 500 && code < 600
+}
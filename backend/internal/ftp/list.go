@@ -0,0 +1,216 @@
+package ftp
+
+import (
+	"path"
+	"spotlive-server/internal/apperr"
+	"strings"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+)
+
+// Tipi voce listing remoto
+const (
+	EntryFile = "file"
+	EntryDir  = "dir"
+	EntryLink = "link"
+)
+
+// Limiti listing ricorsivo (evita di scaricare alberi enormi su box da 1 GB)
+const (
+	DefaultMaxDepth   = 8
+	DefaultMaxEntries = 5000
+)
+
+// Entry voce del listing remoto
+type Entry struct {
+	Path    string    `json:"path"`
+	Name    string    `json:"name"`
+	Type    string    `json:"type"`
+	Size    uint64    `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Target  string    `json:"target,omitempty"`
+}
+
+// Listing risultato listing ricorsivo
+type Listing struct {
+	Root      string  `json:"root"`
+	MLSD      bool    `json:"mlsd"`
+	Entries   []Entry `json:"entries"`
+	Files     int     `json:"files"`
+	Dirs      int     `json:"dirs"`
+	TotalSize uint64  `json:"totalSize"`
+	Truncated bool    `json:"truncated"`
+}
+
+// resolvePath risolve path relativo rispetto a FTPDirectory
+func (c *Client) resolvePath(p string) string {
+	if strings.HasPrefix(p, "/") {
+		return path.Clean(p)
+	}
+	base := c.cfg.FTPDirectory
+	if base == "" {
+		base = "/"
+	}
+	return path.Join(base, p)
+}
+
+// List elenca voci di una directory (MLSD se supportato, altrimenti parsing LIST)
+func (c *Client) List(dir string) ([]Entry, error) {
+	if c.conn == nil {
+		if err := c.Connect(); err != nil {
+			return nil, err
+		}
+	}
+
+	dir = c.resolvePath(dir)
+	entries, err := c.conn.List(dir)
+	if err != nil {
+		if isPermanent(err) {
+			return nil, apperr.Wrap(err, CodeDirNotFound, "FTP list failed").
+				WithDetail("path", dir)
+		}
+		return nil, apperr.Wrap(err, CodeTransferFailed, "FTP list failed").
+			WithDetail("path", dir).
+			AsRetryable()
+	}
+
+	result := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Name == "." || entry.Name == ".." {
+			continue
+		}
+		result = append(result, Entry{
+			Path:    path.Join(dir, entry.Name),
+			Name:    entry.Name,
+			Type:    entryType(entry.Type),
+			Size:    entry.Size,
+			ModTime: entry.Time,
+			Target:  entry.Target,
+		})
+	}
+
+	return result, nil
+}
+
+// ListRecursive elenca ricorsivamente root fino a maxDepth livelli e maxEntries voci
+func (c *Client) ListRecursive(root string, maxDepth, maxEntries int) (*Listing, error) {
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+
+	if c.conn == nil {
+		if err := c.Connect(); err != nil {
+			return nil, err
+		}
+	}
+
+	listing := &Listing{
+		Root:    c.resolvePath(root),
+		MLSD:    c.conn.IsTimePreciseInList(),
+		Entries: []Entry{},
+	}
+
+	type pending struct {
+		dir   string
+		depth int
+	}
+	queue := []pending{{dir: listing.Root, depth: 1}}
+
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		entries, err := c.List(cur.dir)
+		if err != nil {
+			// Root non leggibile è un errore; sottodirectory illeggibili vengono saltate
+			if cur.dir == listing.Root {
+				return nil, err
+			}
+			continue
+		}
+
+		for _, entry := range entries {
+			if len(listing.Entries) >= maxEntries {
+				listing.Truncated = true
+				return listing, nil
+			}
+
+			listing.Entries = append(listing.Entries, entry)
+			switch entry.Type {
+			case EntryDir:
+				listing.Dirs++
+				if cur.depth < maxDepth {
+					queue = append(queue, pending{dir: entry.Path, depth: cur.depth + 1})
+				} else {
+					listing.Truncated = true
+				}
+			case EntryFile:
+				listing.Files++
+				listing.TotalSize += entry.Size
+			}
+		}
+	}
+
+	return listing, nil
+}
+
+// Stat ritorna dimensione e data di un file remoto (MLST, altrimenti SIZE/MDTM)
+func (c *Client) Stat(remotePath string) (*Entry, error) {
+	if c.conn == nil {
+		if err := c.Connect(); err != nil {
+			return nil, err
+		}
+	}
+
+	p := c.resolvePath(remotePath)
+	if entry, err := c.conn.GetEntry(p); err == nil {
+		return &Entry{
+			Path:    p,
+			Name:    path.Base(p),
+			Type:    entryType(entry.Type),
+			Size:    entry.Size,
+			ModTime: entry.Time,
+			Target:  entry.Target,
+		}, nil
+	}
+
+	size, err := c.conn.FileSize(p)
+	if err != nil {
+		if isPermanent(err) {
+			return nil, apperr.Wrap(err, CodeFileNotFound, "FTP stat failed").
+				WithDetail("path", p)
+		}
+		return nil, apperr.Wrap(err, CodeTransferFailed, "FTP stat failed").
+			WithDetail("path", p).
+			AsRetryable()
+	}
+
+	entry := &Entry{
+		Path: p,
+		Name: path.Base(p),
+		Type: EntryFile,
+		Size: uint64(size),
+	}
+	if c.conn.IsGetTimeSupported() {
+		if t, err := c.conn.GetTime(p); err == nil {
+			entry.ModTime = t
+		}
+	}
+	return entry, nil
+}
+
+// entryType converte tipo voce della libreria FTP
+func entryType(t ftp.EntryType) string {
+	switch t {
+	case ftp.EntryTypeFolder:
+		return EntryDir
+	case ftp.EntryTypeLink:
+		return EntryLink
+	default:
+		return EntryFile
+	}
+}
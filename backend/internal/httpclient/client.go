@@ -0,0 +1,190 @@
+// Package httpclient client HTTP condiviso per le chiamate al servlet:
+// timeout di connessione e lettura, proxy, CA aggiuntivi, User-Agent del
+// dispositivo e metriche per endpoint.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"spotlive-server/internal/apperr"
+	"spotlive-server/internal/config"
+	"sync"
+	"time"
+)
+
+// Product prodotto e versione nello User-Agent
+const Product = "SpotLiveScreen/6.0.0"
+
+// clientKey parametri che richiedono un nuovo client se cambiano
+type clientKey struct {
+	connectTimeout int
+	readTimeout    int
+	proxy          string
+	caBundle       string
+}
+
+var (
+	clientMu  sync.Mutex
+	client    *http.Client
+	clientFor clientKey
+)
+
+// Get ritorna il client condiviso per la configurazione corrente (ricreato
+// solo se timeout, proxy o CA cambiano, così le connessioni restano riusate)
+func Get() (*http.Client, error) {
+	cfg := config.Get()
+	key := clientKey{
+		connectTimeout: cfg.HTTPConnectTimeout,
+		readTimeout:    cfg.HTTPReadTimeout,
+		proxy:          cfg.HTTPProxy,
+		caBundle:       cfg.CABundle,
+	}
+	// Config precedente ai campi timeout: mai senza limite
+	if key.connectTimeout <= 0 {
+		key.connectTimeout = 10
+	}
+	if key.readTimeout <= 0 {
+		key.readTimeout = 30
+	}
+
+	clientMu.Lock()
+	defer clientMu.Unlock()
+
+	if client != nil && key == clientFor {
+		return client, nil
+	}
+
+	c, err := build(key)
+	if err != nil {
+		return nil, err
+	}
+	if client != nil {
+		client.CloseIdleConnections()
+	}
+	client, clientFor = c, key
+	return client, nil
+}
+
+// build crea client con transport instrumentato
+func build(key clientKey) (*http.Client, error) {
+	connect := time.Duration(key.connectTimeout) * time.Second
+	read := time.Duration(key.readTimeout) * time.Second
+
+	base := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   connect,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout:   connect,
+		ResponseHeaderTimeout: read,
+		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConnsPerHost:   2,
+	}
+
+	if key.proxy != "" {
+		proxyURL, err := url.Parse(key.proxy)
+		if err != nil {
+			return nil, apperr.Wrap(err, CodeProxyInvalid, "invalid HTTP proxy").
+				WithDetail("httpProxy", key.proxy)
+		}
+		base.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if key.caBundle != "" {
+		pool, err := loadCABundle(key.caBundle)
+		if err != nil {
+			return nil, err
+		}
+		base.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return &http.Client{
+		Transport: &transport{base: base},
+		// Limite complessivo (connessione + header + body): nessuna
+		// richiesta può bloccare un endpoint indefinitamente
+		Timeout: connect + read,
+	}, nil
+}
+
+// Do esegue la richiesta con il client condiviso. Negli errori l'URL è
+// ridotto a schema, host e path: la query può contenere la password.
+func Do(req *http.Request) (*http.Response, error) {
+	c, err := Get()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			redacted := url.URL{Scheme: req.URL.Scheme, Host: req.URL.Host, Path: req.URL.Path}
+			return nil, &url.Error{Op: uerr.Op, URL: redacted.String(), Err: uerr.Err}
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+// loadCABundle CA di sistema più quelli del file PEM
+func loadCABundle(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, apperr.Wrap(err, CodeCABundleInvalid, "CA bundle unreadable").
+			WithDetail("caBundle", path)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, apperr.New(CodeCABundleInvalid, "CA bundle contains no PEM certificates").
+			WithDetail("caBundle", path)
+	}
+	return pool, nil
+}
+
+// UserAgent identifica prodotto e dispositivo verso il servlet
+func UserAgent() string {
+	cfg := config.Get()
+	ua := Product
+	device := ""
+	if cfg.IDMonitor != "" {
+		device = "monitor " + cfg.IDMonitor
+	}
+	if serial := config.DeviceSerial(); serial != "" {
+		if device != "" {
+			device += "; "
+		}
+		device += "serial " + serial
+	}
+	if device != "" {
+		ua += fmt.Sprintf(" (%s)", device)
+	}
+	return ua
+}
+
+// transport imposta User-Agent e registra le metriche
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", UserAgent())
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	record(req, resp, err, time.Since(start))
+	return resp, err
+}
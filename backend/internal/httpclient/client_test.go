@@ -0,0 +1,122 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"spotlive-server/internal/config"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useConfig carica config di default con i campi indicati imposti via flag
+func useConfig(t *testing.T, flags map[string]string) {
+	t.Helper()
+
+	config.SetDataDir(t.TempDir())
+	if err := config.SetOverlay(nil, flags); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		config.SetOverlay(nil, nil)
+		ResetMetrics()
+	})
+}
+
+func TestDoSetsUserAgentAndRecordsMetrics(t *testing.T) {
+	useConfig(t, map[string]string{"idMonitor": "567"})
+
+	var ua string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ua = r.UserAgent()
+		if r.URL.Query().Get("fail") == "1" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	for _, q := range []string{"", "?fail=1"} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/spotlivescreen/XmlServlet"+q, nil)
+		resp, err := Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	if !strings.HasPrefix(ua, Product) || !strings.Contains(ua, "monitor 567") {
+		t.Errorf("User-Agent = %q", ua)
+	}
+
+	host := strings.TrimPrefix(srv.URL, "http://")
+	s, ok := Metrics()[host+"/spotlivescreen/XmlServlet"]
+	if !ok {
+		t.Fatalf("metrics = %+v", Metrics())
+	}
+	if s.Requests != 2 || s.Status["2xx"] != 1 || s.Status["5xx"] != 1 || s.Errors != 0 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestDoReadTimeout(t *testing.T) {
+	useConfig(t, map[string]string{"httpReadTimeout": "1"})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/slow?password=secret", nil)
+	_, err := Do(req)
+	if err == nil {
+		t.Fatal("hung request did not time out")
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("timeout after %v; want ~1s", elapsed)
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("error leaks query: %v", err)
+	}
+	host := strings.TrimPrefix(srv.URL, "http://")
+	if s := Metrics()[host+"/slow"]; s.Errors != 1 {
+		t.Errorf("stats = %+v; want 1 error", s)
+	}
+}
+
+func TestProxyFromConfig(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	useConfig(t, map[string]string{"httpProxy": proxy.URL})
+
+	req, _ := http.NewRequest(http.MethodGet, "http://servlet.invalid/spotlivescreen/XmlServlet", nil)
+	resp, err := Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if u, _ := url.Parse(proxied); u == nil || u.Host != "servlet.invalid" {
+		t.Errorf("proxy received %q", proxied)
+	}
+}
+
+func TestInvalidCABundle(t *testing.T) {
+	useConfig(t, map[string]string{"caBundle": "/nonexistent/ca.pem"})
+
+	req, _ := http.NewRequest(http.MethodGet, "https://servlet.invalid/", nil)
+	if _, err := Do(req); err == nil || !strings.Contains(err.Error(), "CA bundle") {
+		t.Errorf("err = %v; want CA bundle error", err)
+	}
+}
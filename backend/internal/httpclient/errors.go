@@ -0,0 +1,9 @@
+package httpclient
+
+import "spotlive-server/internal/apperr"
+
+// Codici errore del client HTTP
+const (
+	CodeProxyInvalid    apperr.Code = "HTTP_PROXY_INVALID"
+	CodeCABundleInvalid apperr.Code = "HTTP_CA_BUNDLE_INVALID"
+)
@@ -0,0 +1,78 @@
+package httpclient
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// EndpointStats metriche delle richieste verso un endpoint (host + path)
+type EndpointStats struct {
+	Requests  int64            `json:"requests"`
+	Errors    int64            `json:"errors"` // errori di rete (timeout, connessione)
+	Status    map[string]int64 `json:"status"` // per classe: "2xx", "4xx", "5xx"
+	TotalMs   int64            `json:"totalMs"`
+	AvgMs     int64            `json:"avgMs"` // fino agli header di risposta
+	MaxMs     int64            `json:"maxMs"`
+	LastAt    time.Time        `json:"lastAt"`
+	LastError string           `json:"lastError,omitempty"`
+}
+
+var (
+	metricsMu sync.Mutex
+	metrics   = make(map[string]*EndpointStats)
+)
+
+// record registra esito e durata di una richiesta
+func record(req *http.Request, resp *http.Response, err error, elapsed time.Duration) {
+	key := req.URL.Host + req.URL.Path
+	ms := elapsed.Milliseconds()
+
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	s, ok := metrics[key]
+	if !ok {
+		s = &EndpointStats{Status: make(map[string]int64)}
+		metrics[key] = s
+	}
+	s.Requests++
+	s.TotalMs += ms
+	s.AvgMs = s.TotalMs / s.Requests
+	if ms > s.MaxMs {
+		s.MaxMs = ms
+	}
+	s.LastAt = time.Now()
+
+	if err != nil {
+		s.Errors++
+		s.LastError = err.Error()
+		return
+	}
+	s.Status[strconv.Itoa(resp.StatusCode/100)+"xx"]++
+}
+
+// Metrics ritorna copia delle metriche per endpoint
+func Metrics() map[string]EndpointStats {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	out := make(map[string]EndpointStats, len(metrics))
+	for k, s := range metrics {
+		cp := *s
+		cp.Status = make(map[string]int64, len(s.Status))
+		for c, n := range s.Status {
+			cp.Status[c] = n
+		}
+		out[k] = cp
+	}
+	return out
+}
+
+// ResetMetrics azzera le metriche
+func ResetMetrics() {
+	metricsMu.Lock()
+	metrics = make(map[string]*EndpointStats)
+	metricsMu.Unlock()
+}
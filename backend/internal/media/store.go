@@ -0,0 +1,389 @@
+package media
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	blobsDirName  = "blobs"
+	indexFileName = "index.json"
+	tmpDirName    = "tmp"
+	// migratedFileName marcatore: layout flat già importato (una sola volta)
+	migratedFileName = ".flat-migrated"
+)
+
+// Entry voce dell'indice path remoto → blob
+type Entry struct {
+	Hash          string    `json:"hash"`
+	Size          int64     `json:"size"`
+	RemoteSize    int64     `json:"remoteSize,omitempty"`
+	RemoteModTime time.Time `json:"remoteModTime,omitempty"`
+	Source        string    `json:"source,omitempty"`
+	StoredAt      time.Time `json:"storedAt"`
+}
+
+// Remote metadati del file sul server (valori zero = non noti)
+type Remote struct {
+	Size    int64
+	ModTime time.Time
+	// Source identità del file nel CMS (es. "media:42/video"): invariata
+	// se il file viene rinominato sul server
+	Source string
+}
+
+// Stats statistiche dello store
+type Stats struct {
+	Files int   `json:"files"`
+	Blobs int   `json:"blobs"`
+	Bytes int64 `json:"bytes"`
+}
+
+// Store archivio media content-addressed (blob per hash SHA-256 + indice path→hash)
+type Store struct {
+	mu    sync.RWMutex
+	dir   string
+	index map[string]Entry
+}
+
+var (
+	storesMu sync.Mutex
+	stores   = make(map[string]*Store)
+)
+
+// For ritorna lo store per la directory (aperto e migrato alla prima richiesta)
+func For(dir string) (*Store, error) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+
+	if s, ok := stores[dir]; ok {
+		return s, nil
+	}
+
+	s, err := Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	stores[dir] = s
+	return s, nil
+}
+
+// Open apre lo store in dir (vedi MigrateFlat per il vecchio layout)
+func Open(dir string) (*Store, error) {
+	if dir == "" {
+		return nil, errors.New("media directory not set")
+	}
+
+	s := &Store{
+		dir:   dir,
+		index: make(map[string]Entry),
+	}
+
+	// Download interrotti da riavvii precedenti
+	os.RemoveAll(s.tmpDir())
+	for _, d := range []string{s.blobsDir(), s.tmpDir()} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			return nil, fmt.Errorf("media store init failed: %w", err)
+		}
+	}
+
+	data, err := os.ReadFile(s.indexPath())
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &s.index); err != nil {
+			return nil, fmt.Errorf("media index parse failed: %w", err)
+		}
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("media index read failed: %w", err)
+	}
+
+	return s, nil
+}
+
+// Key normalizza path remoto usato come chiave dell'indice
+func Key(remotePath string) string {
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(remotePath)), "/")
+}
+
+// Lookup cerca voce per path remoto. Con dimensione e data remote note
+// (listing corrente) una differenza da quelle salvate è un mancato
+// riscontro: contenuto sostituito sul server con lo stesso path.
+func (s *Store) Lookup(remotePath string, remote Remote) (Entry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, ok := s.index[Key(remotePath)]
+	if !ok {
+		return Entry{}, false
+	}
+	// Voci migrate dal vecchio layout non hanno dati remoti: non confrontabili
+	if remote.Size > 0 && e.RemoteSize > 0 && remote.Size != e.RemoteSize {
+		return Entry{}, false
+	}
+	if !remote.ModTime.IsZero() && !e.RemoteModTime.IsZero() && !remote.ModTime.Equal(e.RemoteModTime) {
+		return Entry{}, false
+	}
+	// Blob rimosso a mano: consideralo assente
+	if _, err := os.Stat(s.BlobPath(e.Hash)); err != nil {
+		return Entry{}, false
+	}
+	return e, true
+}
+
+// FindBySource cerca blob dello stesso file CMS scaricato sotto un altro
+// path (file rinominato sul server). Richiede identità CMS, dimensione e
+// data remote tutte note e uguali: un nuovo upload cambia la data.
+func (s *Store) FindBySource(remote Remote) (Entry, bool) {
+	if remote.Source == "" || remote.Size <= 0 || remote.ModTime.IsZero() {
+		return Entry{}, false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, e := range s.index {
+		if e.Source != remote.Source || e.RemoteSize != remote.Size || !e.RemoteModTime.Equal(remote.ModTime) {
+			continue
+		}
+		if _, err := os.Stat(s.BlobPath(e.Hash)); err == nil {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Link associa path remoto al blob di e (già presente) senza scaricarlo
+func (s *Store) Link(remotePath string, e Entry, remote Remote) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e.RemoteSize = remote.Size
+	e.RemoteModTime = remote.ModTime
+	e.Source = remote.Source
+	e.StoredAt = time.Now()
+	return s.setEntry(Key(remotePath), e)
+}
+
+// Put salva contenuto e lo associa al path remoto. Ritorna dedup=true se
+// il blob era già presente (stesso contenuto sotto altro path).
+func (s *Store) Put(remotePath string, r io.Reader, remote Remote) (e Entry, dedup bool, err error) {
+	tmp, err := os.CreateTemp(s.tmpDir(), "dl-*")
+	if err != nil {
+		return Entry{}, false, err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return Entry{}, false, err
+	}
+
+	e = Entry{
+		Hash:          hex.EncodeToString(h.Sum(nil)),
+		Size:          size,
+		RemoteSize:    remote.Size,
+		RemoteModTime: remote.ModTime,
+		Source:        remote.Source,
+		StoredAt:      time.Now(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	blob := s.BlobPath(e.Hash)
+	if _, err := os.Stat(blob); err == nil {
+		dedup = true
+	} else {
+		if err := os.MkdirAll(filepath.Dir(blob), 0755); err != nil {
+			return Entry{}, false, err
+		}
+		if err := os.Rename(tmp.Name(), blob); err != nil {
+			return Entry{}, false, err
+		}
+	}
+
+	if err := s.setEntry(Key(remotePath), e); err != nil {
+		return Entry{}, false, err
+	}
+	return e, dedup, nil
+}
+
+// setEntry aggiorna l'indice ed elimina il blob sostituito se nessun altro
+// path lo usa (chiamare con lock acquisito)
+func (s *Store) setEntry(key string, e Entry) error {
+	old, replaced := s.index[key]
+	s.index[key] = e
+	if err := s.saveIndex(); err != nil {
+		return err
+	}
+	if replaced && old.Hash != e.Hash {
+		s.releaseBlob(old.Hash)
+	}
+	return nil
+}
+
+// releaseBlob rimuove il blob se non più referenziato dall'indice
+func (s *Store) releaseBlob(hash string) {
+	for _, e := range s.index {
+		if e.Hash == hash {
+			return
+		}
+	}
+	if err := os.Remove(s.BlobPath(hash)); err != nil && !os.IsNotExist(err) {
+		log.Printf("media store: cannot remove unused blob %s: %v", hash, err)
+	}
+}
+
+// BlobPath path locale del blob
+func (s *Store) BlobPath(hash string) string {
+	if len(hash) < 2 {
+		return filepath.Join(s.blobsDir(), hash)
+	}
+	return filepath.Join(s.blobsDir(), hash[:2], hash)
+}
+
+// Stats ritorna numero file indicizzati, blob distinti e byte occupati
+func (s *Store) Stats() Stats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	st := Stats{Files: len(s.index)}
+	seen := make(map[string]bool)
+	for _, e := range s.index {
+		if seen[e.Hash] {
+			continue
+		}
+		seen[e.Hash] = true
+		st.Blobs++
+		st.Bytes += e.Size
+	}
+	return st
+}
+
+// MigrateFlat importa i file salvati col vecchio layout (MediaDir/<basename>).
+// Da chiamare solo per la MediaDir di avvio: eseguita una sola volta
+// (marcatore in dir), non tocca mai directory già migrate o nuove.
+func (s *Store) MigrateFlat() error {
+	marker := filepath.Join(s.dir, migratedFileName)
+	if _, err := os.Stat(marker); err == nil {
+		return nil
+	}
+
+	if err := s.migrateFlat(); err != nil {
+		return err
+	}
+	if err := os.WriteFile(marker, []byte(time.Now().Format(time.RFC3339)+"\n"), 0644); err != nil {
+		return fmt.Errorf("media migration marker write failed: %w", err)
+	}
+	return nil
+}
+
+// migrateFlat importa file regolari di s.dir nello store
+func (s *Store) migrateFlat() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("media dir read failed: %w", err)
+	}
+
+	migrated := 0
+	for _, de := range entries {
+		name := de.Name()
+		if !de.Type().IsRegular() || name == indexFileName || name == indexFileName+".tmp" || strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		oldPath := filepath.Join(s.dir, name)
+		f, err := os.Open(oldPath)
+		if err != nil {
+			log.Printf("media migration: skip %s: %v", name, err)
+			continue
+		}
+
+		// Il vecchio layout perdeva la directory remota: i media erano in upload/
+		_, _, err = s.Put(path.Join("upload", name), f, Remote{})
+		f.Close()
+		if err != nil {
+			log.Printf("media migration: skip %s: %v", name, err)
+			continue
+		}
+
+		if err := os.Remove(oldPath); err != nil {
+			log.Printf("media migration: cannot remove %s: %v", name, err)
+		}
+		migrated++
+	}
+
+	if migrated > 0 {
+		log.Printf("Media store: migrated %d files from flat layout", migrated)
+	}
+	return nil
+}
+
+// Wipe elimina tutti i media (blob, indice, download parziali). Lo store
+// resta utilizzabile e vuoto.
+func (s *Store) Wipe() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range []string{s.blobsDir(), s.tmpDir(), s.indexPath()} {
+		if err := os.RemoveAll(p); err != nil {
+			return fmt.Errorf("media wipe failed: %w", err)
+		}
+	}
+	s.index = make(map[string]Entry)
+
+	for _, d := range []string{s.blobsDir(), s.tmpDir()} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			return fmt.Errorf("media store init failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// saveIndex scrive indice in modo atomico (chiamare con lock acquisito)
+func (s *Store) saveIndex() error {
+	data, err := json.MarshalIndent(s.index, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := s.indexPath() + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.indexPath())
+}
+
+func (s *Store) blobsDir() string  { return filepath.Join(s.dir, blobsDirName) }
+func (s *Store) tmpDir() string    { return filepath.Join(s.dir, tmpDirName) }
+func (s *Store) indexPath() string { return filepath.Join(s.dir, indexFileName) }
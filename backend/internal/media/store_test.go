@@ -0,0 +1,198 @@
+package media
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLookupDetectsReplacedContent(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mod := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	if _, _, err := s.Put("upload/spot.mp4", strings.NewReader("spot A"), Remote{Size: 6, ModTime: mod}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := s.Lookup("upload/spot.mp4", Remote{Size: 6, ModTime: mod}); !ok {
+		t.Error("unchanged remote file not found")
+	}
+	if _, ok := s.Lookup("upload/spot.mp4", Remote{}); !ok {
+		t.Error("lookup without remote metadata missed")
+	}
+	if _, ok := s.Lookup("upload/spot.mp4", Remote{Size: 7, ModTime: mod}); ok {
+		t.Error("size change not detected")
+	}
+	if _, ok := s.Lookup("upload/spot.mp4", Remote{Size: 6, ModTime: mod.Add(time.Minute)}); ok {
+		t.Error("modification time change not detected")
+	}
+
+	// Stessa dimensione e data, path diverso: nessun collegamento implicito
+	if _, ok := s.Lookup("upload/other.mp4", Remote{Size: 6, ModTime: mod}); ok {
+		t.Error("different path matched")
+	}
+}
+
+func TestPutDeduplicatesContent(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, dedup, err := s.Put("upload/a.mp4", strings.NewReader("same"), Remote{})
+	if err != nil || dedup {
+		t.Fatalf("first put: dedup=%v err=%v", dedup, err)
+	}
+	b, dedup, err := s.Put("upload/b.mp4", strings.NewReader("same"), Remote{})
+	if err != nil || !dedup {
+		t.Fatalf("second put: dedup=%v err=%v", dedup, err)
+	}
+	if a.Hash != b.Hash {
+		t.Errorf("hash differs for same content: %s != %s", a.Hash, b.Hash)
+	}
+	if st := s.Stats(); st.Blobs != 1 {
+		t.Errorf("blobs = %d, want 1", st.Blobs)
+	}
+}
+
+func TestPutReleasesReplacedBlob(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old, _, err := s.Put("upload/spot.mp4", strings.NewReader("old"), Remote{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared, _, err := s.Put("upload/shared.mp4", strings.NewReader("shared"), Remote{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Put("upload/copy.mp4", strings.NewReader("shared"), Remote{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Contenuto nuovo sullo stesso path: il vecchio blob non serve più
+	if _, _, err := s.Put("upload/spot.mp4", strings.NewReader("new"), Remote{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(s.BlobPath(old.Hash)); !os.IsNotExist(err) {
+		t.Errorf("replaced blob still on disk: %v", err)
+	}
+
+	// Blob ancora usato da un altro path: resta
+	if _, _, err := s.Put("upload/copy.mp4", strings.NewReader("other"), Remote{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(s.BlobPath(shared.Hash)); err != nil {
+		t.Errorf("shared blob removed: %v", err)
+	}
+}
+
+func TestFindBySourceLinksRenamedFile(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mod := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	remote := Remote{Size: 6, ModTime: mod, Source: "media:42/video"}
+	e, _, err := s.Put("upload/spot.mp4", strings.NewReader("spot A"), remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found, ok := s.FindBySource(remote)
+	if !ok || found.Hash != e.Hash {
+		t.Fatalf("source not found: %+v %v", found, ok)
+	}
+	if _, ok := s.FindBySource(Remote{Size: 6, ModTime: mod.Add(time.Second), Source: "media:42/video"}); ok {
+		t.Error("re-uploaded media matched")
+	}
+	if _, ok := s.FindBySource(Remote{Size: 6, ModTime: mod, Source: "media:43/video"}); ok {
+		t.Error("different media matched")
+	}
+	if _, ok := s.FindBySource(Remote{Source: "media:42/video"}); ok {
+		t.Error("matched without remote metadata")
+	}
+
+	if err := s.Link("upload/renamed.mp4", found, remote); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := s.Lookup("upload/renamed.mp4", remote); !ok || got.Hash != e.Hash {
+		t.Errorf("renamed path not linked: %+v %v", got, ok)
+	}
+}
+
+func TestMigrateFlatRunsOnce(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "spot.mp4"), []byte("flat"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Open non migra
+	if _, ok := s.Lookup("upload/spot.mp4", Remote{}); ok {
+		t.Fatal("Open migrated flat files")
+	}
+
+	if err := s.MigrateFlat(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Lookup("upload/spot.mp4", Remote{}); !ok {
+		t.Error("flat file not migrated")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "spot.mp4")); !os.IsNotExist(err) {
+		t.Errorf("flat file not removed: %v", err)
+	}
+
+	// Già migrata: un file comparso dopo non viene importato
+	if err := os.WriteFile(filepath.Join(dir, "later.mp4"), []byte("later"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.MigrateFlat(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Lookup("upload/later.mp4", Remote{}); ok {
+		t.Error("migration ran twice")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "later.mp4")); err != nil {
+		t.Errorf("file removed by second migration: %v", err)
+	}
+}
+
+func TestWipeRemovesMedia(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, _, err := s.Put("upload/spot.mp4", strings.NewReader("spot"), Remote{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Wipe(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := s.Lookup("upload/spot.mp4", Remote{}); ok {
+		t.Error("entry survived wipe")
+	}
+	if _, err := os.Stat(s.BlobPath(e.Hash)); !os.IsNotExist(err) {
+		t.Errorf("blob survived wipe: %v", err)
+	}
+
+	// Store ancora utilizzabile
+	if _, _, err := s.Put("upload/spot.mp4", strings.NewReader("spot"), Remote{}); err != nil {
+		t.Errorf("put after wipe: %v", err)
+	}
+}
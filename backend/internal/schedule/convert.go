@@ -0,0 +1,225 @@
+package schedule
+
+import (
+	"sort"
+	"spotlive-server/internal/xml"
+	"strings"
+	"time"
+)
+
+// FromXML converte la programmazione del servlet nel modello normalizzato
+func FromXML(s *xml.SchermoXml) *Schedule {
+	out := &Schedule{
+		Version:   Version,
+		Screen:    convertScreen(&s.Schermo),
+		Zones:     []Zone{},
+		Items:     []Item{},
+		Campaigns: []Campaign{},
+		Warnings:  s.Warnings,
+	}
+
+	for _, f := range s.Schermo.Finestre {
+		out.Zones = append(out.Zones, convertZone(f))
+	}
+
+	campagne := make(map[int][]int)
+	for _, p := range s.Programmazioni {
+		c := convertCampaign(&p)
+		for _, id := range c.MediaIDs {
+			campagne[id] = append(campagne[id], c.ID)
+		}
+		out.Campaigns = append(out.Campaigns, c)
+	}
+
+	for _, mf := range s.MediaFinestre {
+		item := Item{
+			ID:       int(mf.ID),
+			Order:    int(mf.Ordine),
+			Type:     mediaType(mf.Tipo, mf.Media.Tipo),
+			Media:    convertMedia(&mf.Media),
+			Campaign: campagne[int(mf.Media.ID)],
+		}
+		if item.Campaign == nil {
+			item.Campaign = []int{}
+		}
+		out.Items = append(out.Items, item)
+	}
+	sort.SliceStable(out.Items, func(i, j int) bool {
+		return out.Items[i].Order < out.Items[j].Order
+	})
+
+	return out
+}
+
+func convertScreen(s *xml.Schermo) Screen {
+	screen := Screen{
+		ID:            int(s.ID),
+		Name:          s.Nome,
+		Address:       s.Indirizzo,
+		Width:         int(s.Larghezza),
+		Height:        int(s.Altezza),
+		Active:        bool(s.Attivo),
+		Category:      convertCategory(s.CategoriaMerceologica),
+		Days:          []time.Weekday{},
+		OpeningHours:  []TimeWindow{},
+		DownloadTimes: []TimeOfDay{},
+		ShowList:      bool(s.Elenco),
+	}
+
+	days := []struct {
+		on  xml.Bool
+		day time.Weekday
+	}{
+		{s.Lun, time.Monday},
+		{s.Mar, time.Tuesday},
+		{s.Mer, time.Wednesday},
+		{s.Gio, time.Thursday},
+		{s.Ven, time.Friday},
+		{s.Sab, time.Saturday},
+		{s.Dom, time.Sunday},
+	}
+	for _, d := range days {
+		if d.on {
+			screen.Days = append(screen.Days, d.day)
+		}
+	}
+
+	for _, o := range s.Orari {
+		start, okStart := timeOfDay(o.OraInizio)
+		end, okEnd := timeOfDay(o.OraFine)
+		if !okStart || !okEnd {
+			continue
+		}
+		screen.OpeningHours = append(screen.OpeningHours, TimeWindow{
+			ID:      int(o.ID),
+			Start:   start,
+			End:     end,
+			Credits: int(o.Crediti),
+		})
+	}
+
+	// "00:00:00" nei campi oraDownloadNN significa slot non usato
+	downloads := []xml.Ora{
+		s.OraDownload01, s.OraDownload02, s.OraDownload03, s.OraDownload04,
+		s.OraDownload05, s.OraDownload06, s.OraDownload07, s.OraDownload08,
+	}
+	for _, ora := range downloads {
+		if t, ok := timeOfDay(ora); ok && t != 0 {
+			screen.DownloadTimes = append(screen.DownloadTimes, t)
+		}
+	}
+
+	if t, ok := timeOfDay(s.OraRestart); ok && t != 0 {
+		screen.RestartTime = &t
+	}
+	return screen
+}
+
+// convertZone: alto è l'offset verticale, destra (nonostante il nome)
+// quello orizzontale dal bordo sinistro, come nel client Windows
+func convertZone(f xml.Finestra) Zone {
+	return Zone{
+		ID:      int(f.ID),
+		Name:    f.Nome,
+		X:       int(f.Destra),
+		Y:       int(f.Alto),
+		Width:   int(f.Larghezza),
+		Height:  int(f.Altezza),
+		Active:  bool(f.Attiva),
+		Spot:    bool(f.Spot),
+		Audio:   bool(f.Audio),
+		Offline: f.ImgNoInternet,
+	}
+}
+
+func convertMedia(m *xml.Media) Media {
+	media := Media{
+		ID:        int(m.ID),
+		Name:      m.Nome,
+		File:      m.Video,
+		Audio:     m.Audio,
+		Thumbnail: m.Miniatura,
+		Duration:  Duration(time.Duration(m.Tempo) * time.Second),
+		Active:    bool(m.Attivo),
+		Approved:  bool(m.Approvato),
+		Public:    bool(m.Pubblico),
+		Credits:   int(m.Crediti),
+		News:      int(m.NumeroNotizie),
+		Category:  convertCategory(m.Categoria),
+	}
+	if media.File == "" {
+		media.File = m.Immagine
+	} else {
+		// Video con immagine: mostrata prima dell'avvio o se il video non parte
+		media.Poster = m.Immagine
+	}
+	if media.File == "" {
+		// Solo audio: la traccia è il file principale
+		media.File, media.Audio = m.Audio, ""
+	}
+	return media
+}
+
+func convertCategory(c xml.Categoria) *Category {
+	if c.ID == 0 && c.Nome == "" {
+		return nil
+	}
+	return &Category{ID: int(c.ID), Name: c.Nome}
+}
+
+func convertCampaign(p *xml.Programmazione) Campaign {
+	c := Campaign{
+		ID:       int(p.ID),
+		Name:     p.Nome,
+		Active:   p.Attiva == nil || bool(*p.Attiva),
+		ZoneIDs:  []int{},
+		MediaIDs: []int{},
+	}
+	if !p.DataInizio.IsZero() {
+		start := p.DataInizio.Time
+		c.Start = &start
+	}
+	if end := p.Fine(); !end.IsZero() {
+		c.End = &end
+	}
+	for _, f := range p.Finestre {
+		c.ZoneIDs = append(c.ZoneIDs, int(f.ID))
+	}
+	for _, m := range p.Media {
+		c.MediaIDs = append(c.MediaIDs, int(m.ID))
+	}
+	return c
+}
+
+// mediaType tipo dal MediaFinestra, in mancanza dal Media
+func mediaType(tipi ...string) MediaType {
+	for _, t := range tipi {
+		switch strings.ToUpper(strings.TrimSpace(t)) {
+		case "VIDEO":
+			return MediaVideo
+		case "IMAGE", "IMMAGINE":
+			return MediaImage
+		case "AUDIO":
+			return MediaAudio
+		case "":
+			continue
+		default:
+			return MediaOther
+		}
+	}
+	return MediaOther
+}
+
+// timeOfDay converte un orario normalizzato (false se assente)
+func timeOfDay(o xml.Ora) (TimeOfDay, bool) {
+	if o == "" {
+		return 0, false
+	}
+	t, err := time.Parse("15:04:05", string(o))
+	if err != nil {
+		return 0, false
+	}
+	return TimeOfDay(time.Duration(t.Hour())*time.Hour +
+		time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second), true
+}
@@ -0,0 +1,134 @@
+package schedule
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"spotlive-server/internal/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+// load decodifica una fixture del package xml
+func load(t *testing.T, name string) *xml.SchermoXml {
+	t.Helper()
+
+	data, err := os.ReadFile("../xml/testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := xml.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestFromXMLScreen(t *testing.T) {
+	s := FromXML(load(t, "multi_zone.xml"))
+
+	if s.Version != Version {
+		t.Errorf("version = %d", s.Version)
+	}
+	if s.Screen.ID != 202 || s.Screen.Width != 1920 || s.Screen.Height != 1080 {
+		t.Errorf("screen = %+v", s.Screen)
+	}
+
+	wantDays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}
+	if !reflect.DeepEqual(s.Screen.Days, wantDays) {
+		t.Errorf("days = %v; want %v", s.Screen.Days, wantDays)
+	}
+
+	// 00:00:00 = slot non usato
+	wantDownloads := []TimeOfDay{TimeOfDay(3 * time.Hour), TimeOfDay(12*time.Hour + 30*time.Minute)}
+	if !reflect.DeepEqual(s.Screen.DownloadTimes, wantDownloads) {
+		t.Errorf("downloadTimes = %v; want %v", s.Screen.DownloadTimes, wantDownloads)
+	}
+	if s.Screen.RestartTime == nil || *s.Screen.RestartTime != TimeOfDay(5*time.Hour) {
+		t.Errorf("restartTime = %v", s.Screen.RestartTime)
+	}
+
+	if len(s.Screen.OpeningHours) != 2 || s.Screen.OpeningHours[1].Start != TimeOfDay(15*time.Hour+30*time.Minute) {
+		t.Errorf("openingHours = %+v", s.Screen.OpeningHours)
+	}
+
+	if len(s.Zones) != 3 {
+		t.Fatalf("zones = %d; want 3", len(s.Zones))
+	}
+	if z := s.Zones[1]; z.X != 1440 || z.Y != 0 || z.Width != 480 {
+		t.Errorf("banner zone = %+v", z)
+	}
+	if z := s.Zones[2]; z.Y != 900 || z.Height != 180 {
+		t.Errorf("ticker zone = %+v", z)
+	}
+}
+
+func TestFromXMLItemsAndCampaigns(t *testing.T) {
+	s := FromXML(load(t, "programmazioni.xml"))
+
+	orders := []int{}
+	for _, it := range s.Items {
+		orders = append(orders, it.Order)
+	}
+	if !reflect.DeepEqual(orders, []int{1, 2, 3}) {
+		t.Errorf("item order = %v; want sorted", orders)
+	}
+
+	first := s.Items[0]
+	if first.Type != MediaImage || first.Media.File != "upload/primavera.jpg" || first.Media.Duration != Duration(10*time.Second) {
+		t.Errorf("first item = %+v", first)
+	}
+	if !reflect.DeepEqual(first.Campaign, []int{101}) {
+		t.Errorf("campaigns = %v; want [101]", first.Campaign)
+	}
+
+	if len(s.Campaigns) != 2 {
+		t.Fatalf("campaigns = %d; want 2", len(s.Campaigns))
+	}
+	estate := s.Campaigns[0]
+	if estate.End == nil || !estate.End.Equal(time.Date(2024, 9, 1, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("end = %v; want exclusive 2024-09-01", estate.End)
+	}
+	if !estate.Active || !s.Campaigns[1].Active {
+		t.Error("campaigns should be active (missing attiva = active)")
+	}
+}
+
+func TestConvertMediaKeepsImageWithVideo(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     xml.Media
+		file   string
+		poster string
+		audio  string
+	}{
+		{"video and image", xml.Media{Video: "upload/spot.mp4", Immagine: "upload/spot.jpg"}, "upload/spot.mp4", "upload/spot.jpg", ""},
+		{"video only", xml.Media{Video: "upload/spot.mp4"}, "upload/spot.mp4", "", ""},
+		{"image only", xml.Media{Immagine: "upload/promo.jpg"}, "upload/promo.jpg", "", ""},
+		{"image and audio", xml.Media{Immagine: "upload/promo.jpg", Audio: "upload/jingle.mp3"}, "upload/promo.jpg", "", "upload/jingle.mp3"},
+		{"audio only", xml.Media{Audio: "upload/jingle.mp3"}, "upload/jingle.mp3", "", ""},
+	}
+	for _, tt := range tests {
+		m := convertMedia(&tt.in)
+		if m.File != tt.file || m.Poster != tt.poster || m.Audio != tt.audio {
+			t.Errorf("%s: file %q, poster %q, audio %q; want %q, %q, %q",
+				tt.name, m.File, m.Poster, m.Audio, tt.file, tt.poster, tt.audio)
+		}
+	}
+}
+
+func TestScheduleJSON(t *testing.T) {
+	data, err := json.Marshal(FromXML(load(t, "multi_zone.xml")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"version":1`, `"downloadTimes":["03:00:00","12:30:00"]`, `"restartTime":"05:00:00"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("JSON missing %s", want)
+		}
+	}
+	if strings.Contains(string(data), "oraDownload") {
+		t.Error("wire field names leaked into v2 JSON")
+	}
+}
@@ -0,0 +1,247 @@
+package schedule
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Diff differenze strutturate tra due versioni della programmazione
+type Diff struct {
+	From         int           `json:"from"`
+	To           int           `json:"to"`
+	Screen       []FieldChange `json:"screen"`
+	Zones        EntityDiff    `json:"zones"`
+	Items        EntityDiff    `json:"items"`
+	Campaigns    EntityDiff    `json:"campaigns"`
+	OpeningHours EntityDiff    `json:"openingHours"`
+}
+
+// EntityDiff entità (per ID) aggiunte, rimosse o modificate
+type EntityDiff struct {
+	Added   []Ref          `json:"added"`
+	Removed []Ref          `json:"removed"`
+	Changed []EntityChange `json:"changed"`
+}
+
+// Ref riferimento leggibile a un'entità
+type Ref struct {
+	ID    int    `json:"id"`
+	Label string `json:"label"`
+}
+
+// EntityChange campi modificati di un'entità
+type EntityChange struct {
+	Ref
+	Fields []FieldChange `json:"fields"`
+}
+
+// FieldChange campo modificato (path JSON, es. "media.file")
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// Empty verifica se non ci sono differenze
+func (d *Diff) Empty() bool {
+	return len(d.Screen) == 0 && d.Zones.empty() && d.Items.empty() &&
+		d.Campaigns.empty() && d.OpeningHours.empty()
+}
+
+func (e *EntityDiff) empty() bool {
+	return len(e.Added) == 0 && len(e.Removed) == 0 && len(e.Changed) == 0
+}
+
+// Summary riepilogo leggibile (per log e storico)
+func (d *Diff) Summary() string {
+	if d.Empty() {
+		return "no changes"
+	}
+	var parts []string
+	if len(d.Screen) > 0 {
+		fields := make([]string, 0, len(d.Screen))
+		for _, f := range d.Screen {
+			fields = append(fields, f.Field)
+		}
+		parts = append(parts, "screen: "+strings.Join(fields, ", "))
+	}
+	for _, section := range []struct {
+		name string
+		diff EntityDiff
+	}{
+		{"zones", d.Zones},
+		{"items", d.Items},
+		{"campaigns", d.Campaigns},
+		{"openingHours", d.OpeningHours},
+	} {
+		if s := section.diff.summary(); s != "" {
+			parts = append(parts, section.name+": "+s)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (e *EntityDiff) summary() string {
+	var parts []string
+	for _, r := range e.Added {
+		parts = append(parts, "+"+r.label())
+	}
+	for _, r := range e.Removed {
+		parts = append(parts, "-"+r.label())
+	}
+	for _, c := range e.Changed {
+		fields := make([]string, 0, len(c.Fields))
+		for _, f := range c.Fields {
+			fields = append(fields, f.Field)
+		}
+		parts = append(parts, "~"+c.Ref.label()+" ("+strings.Join(fields, ", ")+")")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (r Ref) label() string {
+	if r.Label == "" {
+		return "#" + strconv.Itoa(r.ID)
+	}
+	return "#" + strconv.Itoa(r.ID) + " " + r.Label
+}
+
+// Compare calcola le differenze da a a b
+func Compare(a, b *Schedule) Diff {
+	screenA, screenB := a.Screen, b.Screen
+	screenA.OpeningHours, screenB.OpeningHours = nil, nil
+
+	d := Diff{
+		Screen:       compareFields(screenA, screenB),
+		Zones:        compareEntities(zoneEntities(a.Zones), zoneEntities(b.Zones)),
+		Items:        compareEntities(itemEntities(a.Items), itemEntities(b.Items)),
+		Campaigns:    compareEntities(campaignEntities(a.Campaigns), campaignEntities(b.Campaigns)),
+		OpeningHours: compareEntities(windowEntities(a.Screen.OpeningHours), windowEntities(b.Screen.OpeningHours)),
+	}
+	if d.Screen == nil {
+		d.Screen = []FieldChange{}
+	}
+	return d
+}
+
+// entity elemento confrontabile per ID
+type entity struct {
+	ref   Ref
+	value interface{}
+}
+
+func zoneEntities(zones []Zone) []entity {
+	out := make([]entity, 0, len(zones))
+	for _, z := range zones {
+		out = append(out, entity{Ref{z.ID, z.Name}, z})
+	}
+	return out
+}
+
+func itemEntities(items []Item) []entity {
+	out := make([]entity, 0, len(items))
+	for _, it := range items {
+		out = append(out, entity{Ref{it.ID, it.Media.Name}, it})
+	}
+	return out
+}
+
+func campaignEntities(campaigns []Campaign) []entity {
+	out := make([]entity, 0, len(campaigns))
+	for _, c := range campaigns {
+		out = append(out, entity{Ref{c.ID, c.Name}, c})
+	}
+	return out
+}
+
+func windowEntities(windows []TimeWindow) []entity {
+	out := make([]entity, 0, len(windows))
+	for _, w := range windows {
+		out = append(out, entity{Ref{w.ID, w.Start.String() + "-" + w.End.String()}, w})
+	}
+	return out
+}
+
+// compareEntities confronta due liste per ID
+func compareEntities(a, b []entity) EntityDiff {
+	d := EntityDiff{Added: []Ref{}, Removed: []Ref{}, Changed: []EntityChange{}}
+
+	byID := make(map[int]entity, len(a))
+	for _, e := range a {
+		byID[e.ref.ID] = e
+	}
+	seen := make(map[int]bool, len(b))
+	for _, e := range b {
+		seen[e.ref.ID] = true
+		old, ok := byID[e.ref.ID]
+		if !ok {
+			d.Added = append(d.Added, e.ref)
+			continue
+		}
+		if fields := compareFields(old.value, e.value); len(fields) > 0 {
+			d.Changed = append(d.Changed, EntityChange{Ref: e.ref, Fields: fields})
+		}
+	}
+	for _, e := range a {
+		if !seen[e.ref.ID] {
+			d.Removed = append(d.Removed, e.ref)
+		}
+	}
+	return d
+}
+
+// compareFields confronta i campi JSON (oggetti annidati appiattiti con
+// path puntato, array confrontati per intero)
+func compareFields(a, b interface{}) []FieldChange {
+	fa, fb := flatten(a), flatten(b)
+
+	keys := make([]string, 0, len(fa)+len(fb))
+	for k := range fa {
+		keys = append(keys, k)
+	}
+	for k := range fb {
+		if _, ok := fa[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []FieldChange
+	for _, k := range keys {
+		if !reflect.DeepEqual(fa[k], fb[k]) {
+			changes = append(changes, FieldChange{Field: k, From: fa[k], To: fb[k]})
+		}
+	}
+	return changes
+}
+
+// flatten ritorna i campi JSON di v come path → valore
+func flatten(v interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	data, err := json.Marshal(v)
+	if err != nil {
+		return out
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return out
+	}
+	flattenInto(out, "", m)
+	return out
+}
+
+func flattenInto(out map[string]interface{}, prefix string, m map[string]interface{}) {
+	for k, v := range m {
+		if prefix != "" {
+			k = prefix + "." + k
+		}
+		if nested, ok := v.(map[string]interface{}); ok {
+			flattenInto(out, k, nested)
+			continue
+		}
+		out[k] = v
+	}
+}
@@ -0,0 +1,8 @@
+package schedule
+
+import "spotlive-server/internal/apperr"
+
+// Codici errore storico programmazione
+const (
+	CodeVersionNotFound apperr.Code = "SCHEDULE_VERSION_NOT_FOUND"
+)
@@ -0,0 +1,205 @@
+package schedule
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"spotlive-server/internal/apperr"
+	"spotlive-server/internal/xml"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HistorySize versioni della programmazione conservate in memoria
+const HistorySize = 20
+
+// Revision versione registrata della programmazione
+type Revision struct {
+	ID        int       `json:"id"`
+	FetchedAt time.Time `json:"fetchedAt"`
+	Hash      string    `json:"hash"`
+	Schedule  *Schedule `json:"-"`
+}
+
+// RevisionSummary riepilogo versione per /api/schedule/history
+type RevisionSummary struct {
+	ID        int       `json:"id"`
+	FetchedAt time.Time `json:"fetchedAt"`
+	Hash      string    `json:"hash"`
+	Zones     int       `json:"zones"`
+	Items     int       `json:"items"`
+	Campaigns int       `json:"campaigns"`
+	// Changes riepilogo differenze dalla versione precedente
+	Changes string `json:"changes,omitempty"`
+}
+
+var (
+	historyMu sync.RWMutex
+	history   []*Revision
+	summaries = make(map[int]string)
+	nextRevID = 1
+)
+
+// Record registra una programmazione scaricata. Ritorna la nuova versione
+// e le differenze dalla precedente; nil se identica all'ultima.
+func Record(s *Schedule, at time.Time) (*Revision, *Diff) {
+	hash := scheduleHash(s)
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	var prev *Revision
+	if len(history) > 0 {
+		prev = history[len(history)-1]
+		if prev.Hash == hash {
+			return nil, nil
+		}
+	}
+
+	rev := &Revision{ID: nextRevID, FetchedAt: at, Hash: hash, Schedule: s}
+	nextRevID++
+	history = append(history, rev)
+	if len(history) > HistorySize {
+		delete(summaries, history[0].ID)
+		history = history[1:]
+	}
+
+	if prev == nil {
+		return rev, nil
+	}
+	d := Compare(prev.Schedule, s)
+	d.From, d.To = prev.ID, rev.ID
+	summaries[rev.ID] = d.Summary()
+	return rev, &d
+}
+
+// History ritorna le versioni conservate (dalla più vecchia)
+func History() []RevisionSummary {
+	historyMu.RLock()
+	defer historyMu.RUnlock()
+
+	out := make([]RevisionSummary, 0, len(history))
+	for _, r := range history {
+		out = append(out, RevisionSummary{
+			ID:        r.ID,
+			FetchedAt: r.FetchedAt,
+			Hash:      r.Hash,
+			Zones:     len(r.Schedule.Zones),
+			Items:     len(r.Schedule.Items),
+			Campaigns: len(r.Schedule.Campaigns),
+			Changes:   summaries[r.ID],
+		})
+	}
+	return out
+}
+
+// DiffRevisions differenze tra due versioni. from = 0 indica la versione
+// precedente a to, to = 0 l'ultima.
+func DiffRevisions(from, to int) (*Diff, error) {
+	historyMu.RLock()
+	defer historyMu.RUnlock()
+
+	if len(history) == 0 {
+		return nil, apperr.New(CodeVersionNotFound, "no schedule versions recorded")
+	}
+
+	toIdx := len(history) - 1
+	if to != 0 {
+		toIdx = revisionIndex(to)
+		if toIdx < 0 {
+			return nil, apperr.New(CodeVersionNotFound, "schedule version not found").
+				WithDetail("version", to)
+		}
+	}
+
+	fromIdx := toIdx - 1
+	if from != 0 {
+		fromIdx = revisionIndex(from)
+		if fromIdx < 0 {
+			return nil, apperr.New(CodeVersionNotFound, "schedule version not found").
+				WithDetail("version", from)
+		}
+	}
+	if fromIdx < 0 {
+		return nil, apperr.New(CodeVersionNotFound, "no previous schedule version").
+			WithDetail("version", history[toIdx].ID)
+	}
+
+	a, b := history[fromIdx], history[toIdx]
+	d := Compare(a.Schedule, b.Schedule)
+	d.From, d.To = a.ID, b.ID
+	return &d, nil
+}
+
+// ResetHistory dimentica le versioni (schermo cambiato o reset)
+func ResetHistory() {
+	historyMu.Lock()
+	history = nil
+	summaries = make(map[int]string)
+	historyMu.Unlock()
+}
+
+// revisionIndex posizione della versione (-1 se non conservata)
+func revisionIndex(id int) int {
+	for i, r := range history {
+		if r.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// scheduleHash impronta del contenuto (warning esclusi: cambiano con la
+// formattazione ma non con la programmazione)
+func scheduleHash(s *Schedule) string {
+	cp := *s
+	cp.Warnings = nil
+	data, _ := json.Marshal(cp)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// trackPaused programmazioni ignorate da Track (reset in corso)
+var trackPaused atomic.Int32
+
+// PauseTracking sospende la registrazione in Track fino alla chiamata della
+// funzione ritornata: le programmazioni ricevute nel frattempo (scaricate
+// con la configurazione precedente) non entrano nello storico
+func PauseTracking() (resume func()) {
+	trackPaused.Add(1)
+	var once sync.Once
+	return func() { once.Do(func() { trackPaused.Add(-1) }) }
+}
+
+// Track registra ogni programmazione scaricata e logga le differenze
+func Track() {
+	schedules, _ := xml.Subscribe()
+	for s := range schedules {
+		trackSchedule(s, time.Now())
+	}
+}
+
+// trackSchedule registra una programmazione (ignorata durante un reset)
+func trackSchedule(s *xml.SchermoXml, at time.Time) {
+	if trackPaused.Load() > 0 {
+		return
+	}
+
+	normalized := FromXML(s)
+	rev, d := Record(normalized, at)
+	switch {
+	case rev == nil:
+		return
+	case d == nil:
+		log.Printf("Schedule version %d recorded (%s)", rev.ID, rev.Hash)
+	default:
+		log.Printf("Schedule changed (version %d → %d): %s", d.From, d.To, d.Summary())
+	}
+
+	// Nuova versione: segnala configurazioni del CMS da correggere
+	for _, is := range Lint(normalized).Issues {
+		log.Printf("Schedule lint %s %s: %s", is.Severity, is.Code, is.Message)
+	}
+}
@@ -0,0 +1,115 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCompareDetectsChanges(t *testing.T) {
+	a := FromXML(load(t, "programmazioni.xml"))
+	b := FromXML(load(t, "programmazioni.xml"))
+
+	// Media 12 rimosso, media 10 con file diverso, nuova fascia oraria
+	b.Items = b.Items[:2]
+	b.Items[1].Media.File = "upload/estate_v2.mp4"
+	b.Screen.OpeningHours = append(b.Screen.OpeningHours, TimeWindow{ID: 7, Start: TimeOfDay(8 * time.Hour), End: TimeOfDay(12 * time.Hour)})
+	b.Screen.Active = false
+
+	d := Compare(a, b)
+
+	if len(d.Items.Removed) != 1 || d.Items.Removed[0].ID != 3 {
+		t.Errorf("removed = %+v; want item 3", d.Items.Removed)
+	}
+	if len(d.Items.Changed) != 1 || d.Items.Changed[0].ID != 1 {
+		t.Fatalf("changed = %+v; want item 1", d.Items.Changed)
+	}
+	want := []FieldChange{{Field: "media.file", From: "upload/estate.mp4", To: "upload/estate_v2.mp4"}}
+	if !reflect.DeepEqual(d.Items.Changed[0].Fields, want) {
+		t.Errorf("fields = %+v; want %+v", d.Items.Changed[0].Fields, want)
+	}
+	if len(d.OpeningHours.Added) != 1 || d.OpeningHours.Added[0].Label != "08:00:00-12:00:00" {
+		t.Errorf("openingHours added = %+v", d.OpeningHours.Added)
+	}
+	if len(d.Screen) != 1 || d.Screen[0].Field != "active" {
+		t.Errorf("screen = %+v; want active", d.Screen)
+	}
+	if d.Empty() {
+		t.Error("diff reported empty")
+	}
+}
+
+func TestRecordAndDiffRevisions(t *testing.T) {
+	ResetHistory()
+	defer ResetHistory()
+
+	if _, err := DiffRevisions(0, 0); err == nil {
+		t.Error("diff without history accepted")
+	}
+
+	a := FromXML(load(t, "programmazioni.xml"))
+	first, d := Record(a, time.Now())
+	if first == nil || d != nil {
+		t.Fatalf("first record = %v, %v", first, d)
+	}
+	if rev, _ := Record(FromXML(load(t, "programmazioni.xml")), time.Now()); rev != nil {
+		t.Error("identical schedule recorded as new version")
+	}
+
+	b := FromXML(load(t, "programmazioni.xml"))
+	b.Campaigns = b.Campaigns[:1]
+	second, d := Record(b, time.Now())
+	if second == nil || d == nil || len(d.Campaigns.Removed) != 1 {
+		t.Fatalf("second record = %v, %+v", second, d)
+	}
+
+	if got := History(); len(got) != 2 || got[1].Changes == "" {
+		t.Errorf("history = %+v", got)
+	}
+
+	latest, err := DiffRevisions(0, 0)
+	if err != nil || latest.From != first.ID || latest.To != second.ID {
+		t.Errorf("latest diff = %+v, %v", latest, err)
+	}
+	if _, err := DiffRevisions(first.ID+100, 0); err == nil {
+		t.Error("unknown version accepted")
+	}
+	if _, err := DiffRevisions(0, first.ID); err == nil {
+		t.Error("diff before first version accepted")
+	}
+}
+
+func TestHistoryBounded(t *testing.T) {
+	ResetHistory()
+	defer ResetHistory()
+
+	for i := 0; i < HistorySize+5; i++ {
+		s := FromXML(load(t, "programmazioni.xml"))
+		s.Screen.Name = time.Duration(i).String()
+		Record(s, time.Now())
+	}
+	if n := len(History()); n != HistorySize {
+		t.Errorf("history = %d versions; want %d", n, HistorySize)
+	}
+}
+
+func TestPauseTrackingSkipsSchedules(t *testing.T) {
+	ResetHistory()
+	defer ResetHistory()
+
+	s := load(t, "programmazioni.xml")
+	at := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	resume := PauseTracking()
+	trackSchedule(s, at)
+	if h := History(); len(h) != 0 {
+		t.Errorf("schedule recorded while paused: %+v", h)
+	}
+
+	resume()
+	resume() // idempotente
+	trackSchedule(s, at)
+	if h := History(); len(h) != 1 {
+		t.Errorf("history after resume = %+v; want 1 revision", h)
+	}
+}
@@ -0,0 +1,197 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Controlli semantici sulla programmazione: il parser accetta qualsiasi
+// valore, Lint segnala le configurazioni del CMS che lasciano lo schermo
+// nero o parzialmente coperto.
+
+// Severity gravità di una segnalazione
+type Severity string
+
+const (
+	SeverityError   Severity = "error"   // contenuto non visualizzabile
+	SeverityWarning Severity = "warning" // visualizzabile ma probabilmente errato
+)
+
+// Codici stabili delle segnalazioni (usati da web UI e installatori)
+const (
+	LintScreenSizeMissing    = "SCREEN_SIZE_MISSING"
+	LintZoneEmpty            = "ZONE_EMPTY"
+	LintZoneOutOfBounds      = "ZONE_OUT_OF_BOUNDS"
+	LintZoneOverlap          = "ZONE_OVERLAP"
+	LintMediaFileEmpty       = "MEDIA_FILE_EMPTY"
+	LintOpeningHoursInverted = "OPENING_HOURS_INVERTED"
+	LintOpeningHoursEmpty    = "OPENING_HOURS_EMPTY"
+	LintCampaignInverted     = "CAMPAIGN_DATES_INVERTED"
+)
+
+// Issue segnalazione del validatore
+type Issue struct {
+	Code     string   `json:"code"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	Zones    []int    `json:"zones,omitempty"`
+	Item     int      `json:"item,omitempty"`
+	Window   int      `json:"window,omitempty"`
+	Campaign int      `json:"campaign,omitempty"`
+}
+
+// LintReport esito del validatore
+type LintReport struct {
+	Errors   int     `json:"errors"`
+	Warnings int     `json:"warnings"`
+	Issues   []Issue `json:"issues"`
+}
+
+// Lint verifica la coerenza della programmazione
+func Lint(s *Schedule) LintReport {
+	var issues []Issue
+	issues = append(issues, lintZones(s)...)
+	issues = append(issues, lintItems(s)...)
+	issues = append(issues, lintWindows(s)...)
+	issues = append(issues, lintCampaigns(s)...)
+
+	report := LintReport{Issues: []Issue{}}
+	for _, is := range issues {
+		if is.Severity == SeverityError {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+		report.Issues = append(report.Issues, is)
+	}
+	return report
+}
+
+func lintZones(s *Schedule) []Issue {
+	var issues []Issue
+
+	screen := s.Screen
+	checkBounds := screen.Width > 0 && screen.Height > 0
+	if !checkBounds {
+		issues = append(issues, Issue{
+			Code:     LintScreenSizeMissing,
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("screen size %dx%d: zone bounds not checked", screen.Width, screen.Height),
+		})
+	}
+
+	var active []Zone
+	for _, z := range s.Zones {
+		if z.Width <= 0 || z.Height <= 0 {
+			issues = append(issues, Issue{
+				Code:     LintZoneEmpty,
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("zone %q has size %dx%d", z.Name, z.Width, z.Height),
+				Zones:    []int{z.ID},
+			})
+			continue
+		}
+		if checkBounds && (z.X < 0 || z.Y < 0 || z.X+z.Width > screen.Width || z.Y+z.Height > screen.Height) {
+			issues = append(issues, Issue{
+				Code:     LintZoneOutOfBounds,
+				Severity: SeverityError,
+				Message: fmt.Sprintf("zone %q (%d,%d %dx%d) extends beyond screen %dx%d",
+					z.Name, z.X, z.Y, z.Width, z.Height, screen.Width, screen.Height),
+				Zones: []int{z.ID},
+			})
+		}
+		if z.Active {
+			active = append(active, z)
+		}
+	}
+
+	// Solo le zone attive si contendono lo schermo
+	sort.SliceStable(active, func(i, j int) bool { return active[i].ID < active[j].ID })
+	for i := range active {
+		for j := i + 1; j < len(active); j++ {
+			a, b := active[i], active[j]
+			w := min(a.X+a.Width, b.X+b.Width) - max(a.X, b.X)
+			h := min(a.Y+a.Height, b.Y+b.Height) - max(a.Y, b.Y)
+			if w <= 0 || h <= 0 {
+				continue
+			}
+			issues = append(issues, Issue{
+				Code:     LintZoneOverlap,
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("zones %q and %q overlap by %dx%d", a.Name, b.Name, w, h),
+				Zones:    []int{a.ID, b.ID},
+			})
+		}
+	}
+	return issues
+}
+
+func lintItems(s *Schedule) []Issue {
+	var issues []Issue
+	for _, it := range s.Items {
+		if it.Media.File != "" {
+			continue
+		}
+		// RSS e altri tipi possono non avere file: segnalato come warning
+		severity := SeverityError
+		if it.Type == MediaOther {
+			severity = SeverityWarning
+		}
+		issues = append(issues, Issue{
+			Code:     LintMediaFileEmpty,
+			Severity: severity,
+			Message:  fmt.Sprintf("%s media %q has no file", it.Type, it.Media.Name),
+			Item:     it.ID,
+		})
+	}
+	return issues
+}
+
+// lintWindows fine 00:00 = fine giornata (24:00). Una fascia che termina
+// prima di iniziare attraversa la mezzanotte (22:00-02:00): accettata ma
+// segnalata, spesso è un errore di inserimento.
+func lintWindows(s *Schedule) []Issue {
+	var issues []Issue
+	for _, w := range s.Screen.OpeningHours {
+		end := w.End
+		if end == 0 {
+			end = TimeOfDay(24 * time.Hour)
+		}
+		switch {
+		case w.Start < end:
+			continue
+		case w.Start == end:
+			issues = append(issues, Issue{
+				Code:     LintOpeningHoursEmpty,
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("opening hours %s-%s are empty", w.Start, w.End),
+				Window:   w.ID,
+			})
+		default:
+			issues = append(issues, Issue{
+				Code:     LintOpeningHoursInverted,
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("opening hours %s-%s cross midnight", w.Start, w.End),
+				Window:   w.ID,
+			})
+		}
+	}
+	return issues
+}
+
+func lintCampaigns(s *Schedule) []Issue {
+	var issues []Issue
+	for _, c := range s.Campaigns {
+		if c.Start == nil || c.End == nil || c.Start.Before(*c.End) {
+			continue
+		}
+		issues = append(issues, Issue{
+			Code:     LintCampaignInverted,
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("campaign %q ends before it starts: never on air", c.Name),
+			Campaign: c.ID,
+		})
+	}
+	return issues
+}
@@ -0,0 +1,91 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// codes codici delle segnalazioni in ordine
+func codes(r LintReport) []string {
+	out := []string{}
+	for _, is := range r.Issues {
+		out = append(out, is.Code)
+	}
+	return out
+}
+
+func TestLintCleanSchedule(t *testing.T) {
+	r := Lint(FromXML(load(t, "multi_zone.xml")))
+
+	// Solo il ticker RSS senza file, segnalato come warning
+	if got := codes(r); !reflect.DeepEqual(got, []string{LintMediaFileEmpty}) {
+		t.Fatalf("issues = %+v; want only the RSS item", r.Issues)
+	}
+	if is := r.Issues[0]; is.Severity != SeverityWarning || is.Item != 13 {
+		t.Errorf("rss issue = %+v; want warning on item 13", is)
+	}
+	if r.Errors != 0 {
+		t.Errorf("errors = %d; want 0", r.Errors)
+	}
+}
+
+func TestLintDetectsMisconfiguration(t *testing.T) {
+	s := FromXML(load(t, "multi_zone.xml"))
+
+	s.Zones[1].X, s.Zones[1].Width = 1400, 600              // banner sovrapposto al video e oltre il bordo
+	s.Zones[2].Height = 0                                   // ticker vuoto
+	s.Items[0].Media.File = ""                              // video senza file
+	s.Screen.OpeningHours[0].End = TimeOfDay(7 * time.Hour) // 08:00-07:00
+
+	r := Lint(s)
+
+	want := []string{LintZoneOutOfBounds, LintZoneEmpty, LintZoneOverlap, LintMediaFileEmpty, LintMediaFileEmpty, LintOpeningHoursInverted}
+	if got := codes(r); !reflect.DeepEqual(got, want) {
+		t.Fatalf("codes = %v; want %v", got, want)
+	}
+	if r.Errors != 3 || r.Warnings != 3 {
+		t.Errorf("errors/warnings = %d/%d; want 3/3", r.Errors, r.Warnings)
+	}
+	if overlap := r.Issues[2]; !reflect.DeepEqual(overlap.Zones, []int{1, 2}) {
+		t.Errorf("overlap zones = %v; want [1 2]", overlap.Zones)
+	}
+}
+
+func TestLintScreenSizeMissing(t *testing.T) {
+	s := FromXML(load(t, "multi_zone.xml"))
+	s.Screen.Width = 0
+
+	want := []string{LintScreenSizeMissing, LintMediaFileEmpty}
+	if got := codes(Lint(s)); !reflect.DeepEqual(got, want) {
+		t.Errorf("codes = %v; want %v", got, want)
+	}
+}
+
+func TestLintOpeningHoursMidnight(t *testing.T) {
+	at := func(h int) TimeOfDay { return TimeOfDay(time.Duration(h) * time.Hour) }
+	cases := []struct {
+		start, end TimeOfDay
+		want       []string
+	}{
+		{at(18), at(0), []string{}},                         // fino a fine giornata
+		{at(0), at(0), []string{}},                          // tutto il giorno
+		{at(22), at(2), []string{LintOpeningHoursInverted}}, // notturna: warning
+		{at(10), at(10), []string{LintOpeningHoursEmpty}},
+	}
+	for _, c := range cases {
+		s := FromXML(load(t, "multi_zone.xml"))
+		s.Screen.OpeningHours = s.Screen.OpeningHours[:1]
+		s.Screen.OpeningHours[0].Start, s.Screen.OpeningHours[0].End = c.start, c.end
+
+		r := Lint(s)
+		// il ticker RSS della fixture non ha file
+		want := append([]string{LintMediaFileEmpty}, c.want...)
+		if got := codes(r); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s-%s: codes = %v; want %v", c.start, c.end, got, want)
+		}
+		if c.start == at(22) && r.Errors != 0 {
+			t.Errorf("overnight window reported as error")
+		}
+	}
+}
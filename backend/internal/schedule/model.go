@@ -0,0 +1,140 @@
+// Package schedule modello normalizzato della programmazione, indipendente
+// dal formato XML del servlet (vedi package xml per il formato wire).
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"spotlive-server/internal/xml"
+	"time"
+)
+
+// Version versione del modello: incrementare a ogni modifica incompatibile
+// del JSON esposto su /api/v2/schedule
+const Version = 1
+
+// Schedule programmazione normalizzata
+type Schedule struct {
+	Version   int           `json:"version"`
+	Screen    Screen        `json:"screen"`
+	Zones     []Zone        `json:"zones"`
+	Items     []Item        `json:"items"`
+	Campaigns []Campaign    `json:"campaigns"`
+	Warnings  []xml.Warning `json:"warnings,omitempty"`
+}
+
+// Screen display fisico
+type Screen struct {
+	ID       int       `json:"id"`
+	Name     string    `json:"name"`
+	Address  string    `json:"address"`
+	Width    int       `json:"width"`
+	Height   int       `json:"height"`
+	Active   bool      `json:"active"`
+	Category *Category `json:"category,omitempty"`
+	// Days giorni di accensione (time.Weekday: 0 = domenica)
+	Days []time.Weekday `json:"days"`
+	// OpeningHours fasce orarie di accensione
+	OpeningHours  []TimeWindow `json:"openingHours"`
+	DownloadTimes []TimeOfDay  `json:"downloadTimes"`
+	RestartTime   *TimeOfDay   `json:"restartTime"`
+	ShowList      bool         `json:"showList"`
+}
+
+// Zone area rettangolare dello schermo (pixel)
+type Zone struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	X       int    `json:"x"`
+	Y       int    `json:"y"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+	Active  bool   `json:"active"`
+	Spot    bool   `json:"spot"`
+	Audio   bool   `json:"audio"`
+	Offline string `json:"offlineImage,omitempty"` // immagine senza connessione
+}
+
+// Item media in programmazione
+type Item struct {
+	ID       int       `json:"id"`
+	Order    int       `json:"order"`
+	Type     MediaType `json:"type"`
+	Media    Media     `json:"media"`
+	Campaign []int     `json:"campaigns"` // ID campagne (vuoto = sempre in onda)
+}
+
+// MediaType tipo di contenuto
+type MediaType string
+
+const (
+	MediaVideo MediaType = "video"
+	MediaImage MediaType = "image"
+	MediaAudio MediaType = "audio"
+	MediaOther MediaType = "other"
+)
+
+// Media contenuto multimediale
+type Media struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	File      string    `json:"file"`             // path remoto (es: upload/video.mp4)
+	Poster    string    `json:"poster,omitempty"` // immagine di un video: copertina e fallback
+	Audio     string    `json:"audio,omitempty"`
+	Thumbnail string    `json:"thumbnail,omitempty"`
+	Duration  Duration  `json:"duration"`
+	Active    bool      `json:"active"`
+	Approved  bool      `json:"approved"`
+	Public    bool      `json:"public"`
+	Credits   int       `json:"credits"`
+	News      int       `json:"news,omitempty"`
+	Category  *Category `json:"category,omitempty"`
+}
+
+// Category categoria merceologica
+type Category struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// Campaign campagna pubblicitaria
+type Campaign struct {
+	ID    int        `json:"id"`
+	Name  string     `json:"name"`
+	Start *time.Time `json:"start"`
+	// End istante (escluso) di fine validità; nil = senza scadenza
+	End      *time.Time `json:"end"`
+	Active   bool       `json:"active"`
+	ZoneIDs  []int      `json:"zones"`
+	MediaIDs []int      `json:"media"`
+}
+
+// TimeWindow fascia oraria giornaliera [Start, End)
+type TimeWindow struct {
+	ID      int       `json:"id"`
+	Start   TimeOfDay `json:"start"`
+	End     TimeOfDay `json:"end"`
+	Credits int       `json:"credits"`
+}
+
+// TimeOfDay orario come distanza dalla mezzanotte ("HH:MM:SS" in JSON)
+type TimeOfDay time.Duration
+
+// String formatta come HH:MM:SS
+func (t TimeOfDay) String() string {
+	s := int(time.Duration(t) / time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", s/3600, s/60%60, s%60)
+}
+
+// MarshalJSON serializza come "HH:MM:SS"
+func (t TimeOfDay) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// Duration durata (secondi in JSON)
+type Duration time.Duration
+
+// MarshalJSON serializza in secondi
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).Seconds())
+}
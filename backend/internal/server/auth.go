@@ -0,0 +1,94 @@
+package server
+
+import (
+	"net"
+	"net/url"
+	"spotlive-server/internal/apperr"
+	"spotlive-server/internal/auth"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminTokenHeader header alternativo a "Authorization: Bearer <token>"
+const AdminTokenHeader = "X-Admin-Token"
+
+// RequireAdmin protegge endpoint che modificano stato del dispositivo.
+// Le richieste da loopback (WebView sul dispositivo) sono considerate fidate
+// solo se anche Host e Origin sono loopback: una pagina esterna aperta sul
+// dispositivo (DNS rebinding, POST cross-origin) non ottiene privilegi.
+// Quelle dalla LAN devono presentare il token admin.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isAdmin(c) {
+			c.Next()
+			return
+		}
+
+		respondError(c, apperr.New(CodeAdminAuthRequired, "Admin token required").
+			WithDetail("header", AdminTokenHeader))
+	}
+}
+
+// RequireAdminToken come RequireAdmin ma senza eccezione per loopback:
+// per operazioni distruttive il token è sempre obbligatorio
+func RequireAdminToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if auth.Check(requestToken(c)) {
+			c.Next()
+			return
+		}
+
+		respondError(c, apperr.New(CodeAdminAuthRequired, "Admin token required").
+			WithDetail("header", AdminTokenHeader))
+	}
+}
+
+// isAdmin verifica se la richiesta ha privilegi admin (loopback o token)
+func isAdmin(c *gin.Context) bool {
+	return isLoopback(c) || auth.Check(requestToken(c))
+}
+
+// isLoopback verifica indirizzo TCP reale del client (non X-Forwarded-For),
+// Host della richiesta e Origin se presente
+func isLoopback(c *gin.Context) bool {
+	ip := net.ParseIP(c.RemoteIP())
+	if ip == nil || !ip.IsLoopback() {
+		return false
+	}
+	if !loopbackHost(c.Request.Host) {
+		return false
+	}
+	if origin := c.GetHeader("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || !loopbackHost(u.Host) {
+			return false
+		}
+	}
+	return true
+}
+
+// loopbackHost verifica che host[:porta] sia localhost o un indirizzo loopback
+func loopbackHost(hostport string) bool {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// requestToken estrae token da Authorization Bearer o X-Admin-Token
+func requestToken(c *gin.Context) string {
+	if token := c.GetHeader(AdminTokenHeader); token != "" {
+		return token
+	}
+	if h := c.GetHeader("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+	}
+	return ""
+}
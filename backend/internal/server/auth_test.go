@@ -0,0 +1,122 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"spotlive-server/internal/auth"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testAdminToken = "test-admin-token"
+
+func newAuthRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+
+	if err := auth.Init(t.TempDir(), testAdminToken); err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+	r.POST("/admin", RequireAdmin(), ok)
+	r.POST("/token", RequireAdminToken(), ok)
+	return r
+}
+
+// authRequest richiesta con indirizzo client, Host e header indicati
+func authRequest(target, remoteAddr, host string, headers map[string]string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, target, nil)
+	req.RemoteAddr = remoteAddr
+	req.Host = host
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return req
+}
+
+func TestRequireAdmin(t *testing.T) {
+	r := newAuthRouter(t)
+
+	tests := []struct {
+		name    string
+		remote  string
+		host    string
+		headers map[string]string
+		want    int
+	}{
+		{"loopback webview", "127.0.0.1:40000", "localhost:8080", nil, http.StatusOK},
+		{"loopback ip host", "127.0.0.1:40000", "127.0.0.1:8080", nil, http.StatusOK},
+		{"loopback ipv6", "[::1]:40000", "[::1]:8080", nil, http.StatusOK},
+		{"loopback same origin", "127.0.0.1:40000", "localhost:8080",
+			map[string]string{"Origin": "http://localhost:8080"}, http.StatusOK},
+		{"dns rebinding", "127.0.0.1:40000", "evil.example:8080", nil, http.StatusUnauthorized},
+		{"cross-origin post", "127.0.0.1:40000", "127.0.0.1:8080",
+			map[string]string{"Origin": "http://evil.example", "Content-Type": "text/plain"}, http.StatusUnauthorized},
+		{"opaque origin", "127.0.0.1:40000", "localhost:8080",
+			map[string]string{"Origin": "null"}, http.StatusUnauthorized},
+		{"lan without token", "192.168.1.20:40000", "192.168.1.10:8080", nil, http.StatusUnauthorized},
+		{"lan forged forwarded-for", "192.168.1.20:40000", "localhost:8080",
+			map[string]string{"X-Forwarded-For": "127.0.0.1"}, http.StatusUnauthorized},
+		{"lan wrong token", "192.168.1.20:40000", "192.168.1.10:8080",
+			map[string]string{AdminTokenHeader: "wrong"}, http.StatusUnauthorized},
+		{"lan header token", "192.168.1.20:40000", "192.168.1.10:8080",
+			map[string]string{AdminTokenHeader: testAdminToken}, http.StatusOK},
+		{"lan bearer token", "192.168.1.20:40000", "192.168.1.10:8080",
+			map[string]string{"Authorization": "Bearer " + testAdminToken}, http.StatusOK},
+		{"rebinding with token", "127.0.0.1:40000", "evil.example:8080",
+			map[string]string{AdminTokenHeader: testAdminToken}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, authRequest("/admin", tt.remote, tt.host, tt.headers))
+		if w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d (%s)", tt.name, w.Code, tt.want, w.Body.String())
+		}
+	}
+}
+
+func TestRequireAdminToken(t *testing.T) {
+	r := newAuthRouter(t)
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    int
+	}{
+		{"loopback without token", "127.0.0.1:40000", nil, http.StatusUnauthorized},
+		{"loopback wrong token", "127.0.0.1:40000", map[string]string{AdminTokenHeader: "wrong"}, http.StatusUnauthorized},
+		{"empty bearer", "127.0.0.1:40000", map[string]string{"Authorization": "Bearer "}, http.StatusUnauthorized},
+		{"loopback token", "127.0.0.1:40000", map[string]string{AdminTokenHeader: testAdminToken}, http.StatusOK},
+		{"lan bearer token", "192.168.1.20:40000", map[string]string{"Authorization": "Bearer " + testAdminToken}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, authRequest("/token", tt.remote, "localhost:8080", tt.headers))
+		if w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d (%s)", tt.name, w.Code, tt.want, w.Body.String())
+		}
+	}
+}
+
+func TestLoopbackHost(t *testing.T) {
+	tests := map[string]bool{
+		"localhost":          true,
+		"LOCALHOST:8080":     true,
+		"localhost.:8080":    true,
+		"127.0.0.1":          true,
+		"127.0.0.2:8080":     true,
+		"[::1]:8080":         true,
+		"":                   false,
+		"evil.example":       false,
+		"localhost.evil.com": false,
+		"192.168.1.10:8080":  false,
+	}
+	for host, want := range tests {
+		if got := loopbackHost(host); got != want {
+			t.Errorf("loopbackHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
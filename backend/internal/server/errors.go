@@ -0,0 +1,125 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"spotlive-server/internal/apperr"
+	"spotlive-server/internal/config"
+	"spotlive-server/internal/ftp"
+	"spotlive-server/internal/httpclient"
+	"spotlive-server/internal/schedule"
+	"spotlive-server/internal/xml"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Codici errore del server HTTP
+const (
+	CodeInvalidPath       apperr.Code = "MEDIA_PATH_INVALID"
+	CodeMediaNotAllowed   apperr.Code = "MEDIA_NOT_ALLOWED"
+	CodeUploadTooLarge    apperr.Code = "UPLOAD_TOO_LARGE"
+	CodeUnsupportedMedia  apperr.Code = "UPLOAD_UNSUPPORTED_TYPE"
+	CodeAdminAuthRequired apperr.Code = "ADMIN_AUTH_REQUIRED"
+	CodeResetInProgress   apperr.Code = "RESET_IN_PROGRESS"
+)
+
+// APIError envelope errore restituito da tutti gli endpoint
+type APIError struct {
+	Code      apperr.Code            `json:"code"`
+	Message   string                 `json:"message"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	Retryable bool                   `json:"retryable"`
+}
+
+// ErrorResponse risposta di errore standard
+type ErrorResponse struct {
+	Success bool      `json:"success"`
+	Error   *APIError `json:"error"`
+}
+
+// statusByCode mappa codici errore a status HTTP
+var statusByCode = map[apperr.Code]int{
+	apperr.CodeInvalidRequest: http.StatusBadRequest,
+	apperr.CodeNotFound:       http.StatusNotFound,
+
+	CodeInvalidPath:       http.StatusBadRequest,
+	CodeMediaNotAllowed:   http.StatusForbidden,
+	CodeUploadTooLarge:    http.StatusRequestEntityTooLarge,
+	CodeUnsupportedMedia:  http.StatusUnsupportedMediaType,
+	CodeAdminAuthRequired: http.StatusUnauthorized,
+	CodeResetInProgress:   http.StatusConflict,
+
+	config.CodeMissingFields:      http.StatusBadRequest,
+	config.CodeInvalidFields:      http.StatusBadRequest,
+	config.CodeNotConfigured:      http.StatusConflict,
+	config.CodeDataDirNotSet:      http.StatusInternalServerError,
+	config.CodeReadFailed:         http.StatusInternalServerError,
+	config.CodeDecryptFailed:      http.StatusInternalServerError,
+	config.CodeTampered:           http.StatusInternalServerError,
+	config.CodeLegacyRejected:     http.StatusInternalServerError,
+	config.CodeKeyFailed:          http.StatusInternalServerError,
+	config.CodeParseFailed:        http.StatusInternalServerError,
+	config.CodeWriteFailed:        http.StatusInternalServerError,
+	config.CodeUnsupportedVersion: http.StatusInternalServerError,
+	config.CodeBundleInvalid:      http.StatusBadRequest,
+	config.CodeBundleAuth:         http.StatusBadRequest,
+
+	ftp.CodeNotConfigured:  http.StatusConflict,
+	ftp.CodeUnreachable:    http.StatusBadGateway,
+	ftp.CodeAuthFailed:     http.StatusBadGateway,
+	ftp.CodeDirNotFound:    http.StatusBadGateway,
+	ftp.CodeFileNotFound:   http.StatusNotFound,
+	ftp.CodeTransferFailed: http.StatusBadGateway,
+
+	xml.CodeInvalidServerURL:  http.StatusBadRequest,
+	xml.CodeServerUnreachable: http.StatusBadGateway,
+	xml.CodeServerAuthFailed:  http.StatusBadGateway,
+	xml.CodeServerHTTPError:   http.StatusBadGateway,
+	xml.CodeScheduleRead:      http.StatusBadGateway,
+	xml.CodeScheduleParse:     http.StatusBadGateway,
+	xml.CodeScheduleTooLarge:  http.StatusBadGateway,
+
+	schedule.CodeVersionNotFound: http.StatusNotFound,
+
+	httpclient.CodeProxyInvalid:    http.StatusInternalServerError,
+	httpclient.CodeCABundleInvalid: http.StatusInternalServerError,
+}
+
+// toAPIError converte errore in envelope (errori non tipizzati → INTERNAL_ERROR)
+func toAPIError(err error) *APIError {
+	if e := apperr.From(err); e != nil {
+		return &APIError{
+			Code:      e.Code,
+			Message:   e.Error(),
+			Details:   e.Details,
+			Retryable: e.Retryable,
+		}
+	}
+	return &APIError{
+		Code:    apperr.CodeInternal,
+		Message: err.Error(),
+	}
+}
+
+// respondError scrive errore tipizzato con status HTTP coerente
+func respondError(c *gin.Context, err error) {
+	apiErr := toAPIError(err)
+
+	status, ok := statusByCode[apiErr.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	if status >= 500 {
+		log.Printf("API error %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+	}
+
+	c.AbortWithStatusJSON(status, ErrorResponse{
+		Success: false,
+		Error:   apiErr,
+	})
+}
+
+// badRequest risponde con INVALID_REQUEST
+func badRequest(c *gin.Context, message string) {
+	respondError(c, apperr.New(apperr.CodeInvalidRequest, message))
+}
@@ -1,338 +1,715 @@
-package server
-
-import (
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
-	"os"
-	"path/filepath"
-	"spotlive-server/internal/config"
-	"spotlive-server/internal/ftp"
-	"spotlive-server/internal/xml"
-
-	"github.com/gin-gonic/gin"
-)
-
-// ConfigRequest struttura richiesta configurazione
-type ConfigRequest struct {
-	ServerURL    string `json:"serverUrl"`
-	Username     string `json:"username"`
-	Password     string `json:"password"`
-	IDMonitor    string `json:"idMonitor"`
-	UserSchermo  string `json:"userSchermo"`
-}
-
-// ConfigResponse struttura risposta configurazione
-type ConfigResponse struct {
-	Configured bool   `json:"configured"`
-	Config     *config.Config `json:"config,omitempty"`
-}
-
-// ScheduleResponse struttura risposta programmazione
-type ScheduleResponse struct {
-	Success  bool            `json:"success"`
-	Schedule *xml.SchermoXml `json:"schedule,omitempty"`
-	Error    string          `json:"error,omitempty"`
-}
-
-// DownloadResponse struttura risposta download
-type DownloadResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
-	Error   string `json:"error,omitempty"`
-}
-
-// GetConfig ritorna configurazione corrente
-func GetConfig(c *gin.Context) {
-	cfg := config.Get()
-	isConfigured := config.IsConfigured()
-
-	response := ConfigResponse{
-		Configured: isConfigured,
-	}
-
-	if isConfigured {
-		// Non esporre password
-		safeCfg := *cfg
-		safeCfg.Password = "***"
-		safeCfg.FTPPassword = "***"
-		response.Config = &safeCfg
-	}
-
-	c.JSON(200, response)
-}
-
-// SaveConfig salva nuova configurazione
-func SaveConfig(c *gin.Context) {
-	var req ConfigRequest
-	if err := c.BindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid request"})
-		return
-	}
-
-	// Valida campi obbligatori
-	if req.Username == "" || req.Password == "" || req.IDMonitor == "" {
-		c.JSON(400, gin.H{"error": "Username, password and idMonitor are required"})
-		return
-	}
-
-	// Crea nuova config
-	cfg := config.GetDefault()
-	if req.ServerURL != "" {
-		cfg.ServerURL = req.ServerURL
-	}
-	cfg.Username = req.Username
-	cfg.Password = req.Password
-	cfg.IDMonitor = req.IDMonitor
-	cfg.UserSchermo = req.UserSchermo
-
-	// Salva
-	if err := config.Save(cfg); err != nil {
-		c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to save config: %v", err)})
-		return
-	}
-
-	c.JSON(200, gin.H{"success": true, "message": "Configuration saved"})
-}
-
-// TestConnection testa connessione al server
-func TestConnection(c *gin.Context) {
-	// Prova a scaricare programmazione
-	schedule, err := xml.FetchSchedule()
-	if err != nil {
-		c.JSON(500, gin.H{
-			"success": false,
-			"error":   fmt.Sprintf("Connection test failed: %v", err),
-		})
-		return
-	}
-
-	c.JSON(200, gin.H{
-		"success": true,
-		"message": "Connection successful",
-		"schermo": schedule.Schermo.Nome,
-	})
-}
-
-// GetSchedule scarica programmazione dal server
-func GetSchedule(c *gin.Context) {
-	schedule, err := xml.FetchSchedule()
-	if err != nil {
-		c.JSON(500, ScheduleResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
-		return
-	}
-
-	c.JSON(200, ScheduleResponse{
-		Success:  true,
-		Schedule: schedule,
-	})
-}
-
-// DownloadMedia scarica file media via FTP proxy
-func DownloadMedia(c *gin.Context) {
-	filename := c.Param("filename")
-	if filename == "" {
-		c.JSON(400, gin.H{"error": "Filename required"})
-		return
-	}
-
-	// Path relativo (es: "upload/video.mp4")
-	remotePath := filename
-	if filepath.Dir(filename) == "." {
-		remotePath = "upload/" + filename
-	}
-
-	// Connetti FTP
-	ftpClient := ftp.NewClient()
-	defer ftpClient.Close()
-
-	if err := ftpClient.Connect(); err != nil {
-		c.JSON(500, gin.H{"error": fmt.Sprintf("FTP connection failed: %v", err)})
-		return
-	}
-
-	// Download
-	stream, err := ftpClient.Download(remotePath)
-	if err != nil {
-		c.JSON(500, gin.H{"error": fmt.Sprintf("FTP download failed: %v", err)})
-		return
-	}
-	defer stream.Close()
-
-	// Determina content type
-	contentType := "application/octet-stream"
-	ext := filepath.Ext(filename)
-	switch ext {
-	case ".mp4":
-		contentType = "video/mp4"
-	case ".jpg", ".jpeg":
-		contentType = "image/jpeg"
-	case ".png":
-		contentType = "image/png"
-	case ".gif":
-		contentType = "image/gif"
-	}
-
-	// Stream al client
-	c.Header("Content-Type", contentType)
-	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", filepath.Base(filename)))
-	c.Status(200)
-
-	// Copia stream
-	if _, err := io.Copy(c.Writer, stream); err != nil {
-		// Errore già dopo headers inviati, non possiamo più rispondere con JSON
-		fmt.Printf("Error streaming file: %v\n", err)
-	}
-}
-
-// CacheMedia salva media in cache locale
-func CacheMedia(c *gin.Context) {
-	filename := c.Param("filename")
-	if filename == "" {
-		c.JSON(400, gin.H{"error": "Filename required"})
-		return
-	}
-
-	cfg := config.Get()
-	cachePath := filepath.Join(cfg.CacheDir, filepath.Base(filename))
-
-	// Ricevi file dal client
-	file, err := c.FormFile("file")
-	if err != nil {
-		c.JSON(400, gin.H{"error": "File required"})
-		return
-	}
-
-	// Salva
-	if err := c.SaveUploadedFile(file, cachePath); err != nil {
-		c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to save file: %v", err)})
-		return
-	}
-
-	c.JSON(200, gin.H{"success": true, "path": cachePath})
-}
-
-// GetCachedMedia ritorna media dalla cache
-func GetCachedMedia(c *gin.Context) {
-	filename := c.Param("filename")
-	if filename == "" {
-		c.JSON(400, gin.H{"error": "Filename required"})
-		return
-	}
-
-	cfg := config.Get()
-	cachePath := filepath.Join(cfg.CacheDir, filepath.Base(filename))
-
-	// Verifica esistenza
-	if _, err := os.Stat(cachePath); os.IsNotExist(err) {
-		c.JSON(404, gin.H{"error": "File not found in cache"})
-		return
-	}
-
-	c.File(cachePath)
-}
-
-// DownloadAllMedia scarica tutti i media della programmazione
-func DownloadAllMedia(c *gin.Context) {
-	// Scarica programmazione
-	schedule, err := xml.FetchSchedule()
-	if err != nil {
-		c.JSON(500, DownloadResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Failed to fetch schedule: %v", err),
-		})
-		return
-	}
-
-	// Lista files da scaricare
-	files := schedule.GetMediaFiles()
-
-	// Connetti FTP
-	ftpClient := ftp.NewClient()
-	defer ftpClient.Close()
-
-	if err := ftpClient.Connect(); err != nil {
-		c.JSON(500, DownloadResponse{
-			Success: false,
-			Error:   fmt.Sprintf("FTP connection failed: %v", err),
-		})
-		return
-	}
-
-	cfg := config.Get()
-	downloaded := 0
-	skipped := 0
-	errors := 0
-
-	for _, filePathRel := range files {
-		// Path locale
-		localPath := filepath.Join(cfg.MediaDir, filepath.Base(filePathRel))
-
-		// Salta se già esiste
-		if _, err := os.Stat(localPath); err == nil {
-			skipped++
-			continue
-		}
-
-		// Download
-		stream, err := ftpClient.Download(filePathRel)
-		if err != nil {
-			fmt.Printf("Error downloading %s: %v\n", filePathRel, err)
-			errors++
-			continue
-		}
-
-		// Salva localmente
-		outFile, err := os.Create(localPath)
-		if err != nil {
-			stream.Close()
-			errors++
-			continue
-		}
-
-		_, err = io.Copy(outFile, stream)
-		outFile.Close()
-		stream.Close()
-
-		if err != nil {
-			errors++
-			continue
-		}
-
-		downloaded++
-	}
-
-	c.JSON(200, DownloadResponse{
-		Success: true,
-		Message: fmt.Sprintf("Downloaded: %d, Skipped: %d, Errors: %d", downloaded, skipped, errors),
-	})
-}
-
-// SendHeartbeat invia update al server
-func SendHeartbeat(c *gin.Context) {
-	if err := xml.SendUpdate(); err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(200, gin.H{"success": true})
-}
-
-// GetStatus ritorna status del player
-func GetStatus(c *gin.Context) {
-	cfg := config.Get()
-
-	// Conta file in cache
-	mediaFiles, _ := os.ReadDir(cfg.MediaDir)
-
-	c.JSON(200, gin.H{
-		"configured":  config.IsConfigured(),
-		"idMonitor":   cfg.IDMonitor,
-		"mediaCount":  len(mediaFiles),
-		"version":     "6.0.0",
-	})
-}
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"spotlive-server/internal/apperr"
+	"spotlive-server/internal/config"
+	"spotlive-server/internal/ftp"
+	"spotlive-server/internal/httpclient"
+	"spotlive-server/internal/media"
+	"spotlive-server/internal/schedule"
+	"spotlive-server/internal/xml"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigRequest struttura richiesta configurazione
+type ConfigRequest struct {
+	ServerURL    string `json:"serverUrl"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	IDMonitor    string `json:"idMonitor"`
+	UserSchermo  string `json:"userSchermo"`
+}
+
+// ConfigResponse struttura risposta configurazione
+type ConfigResponse struct {
+	Configured bool   `json:"configured"`
+	Config     *config.Config `json:"config,omitempty"`
+}
+
+// ConfigUpdateResponse risposta salvataggio configurazione
+type ConfigUpdateResponse struct {
+	Success bool           `json:"success"`
+	Message string         `json:"message"`
+	Changed []string       `json:"changed"`
+	Config  *config.Config `json:"config"`
+}
+
+// EffectiveConfigResponse valori effettivi con provenienza (default, file, env, flag)
+type EffectiveConfigResponse struct {
+	Success bool               `json:"success"`
+	Fields  []config.FieldInfo `json:"fields"`
+}
+
+// ExportRequest parametri export bundle di provisioning
+type ExportRequest struct {
+	Passphrase        string            `json:"passphrase"`
+	IDMonitorTemplate string            `json:"idMonitorTemplate"`
+	Monitors          map[string]string `json:"monitors"`
+}
+
+// ImportRequest bundle di provisioning da applicare
+type ImportRequest struct {
+	Passphrase string          `json:"passphrase"`
+	Serial     string          `json:"serial"` // default: seriale del dispositivo
+	Bundle     json.RawMessage `json:"bundle"`
+}
+
+// ScheduleResponse struttura risposta programmazione
+type ScheduleResponse struct {
+	Success  bool                 `json:"success"`
+	Schedule *xml.SchermoXml      `json:"schedule,omitempty"`
+	Playlist []xml.MediaFinestra  `json:"playlist"` // media in onda adesso (campagne scadute escluse)
+	Lint     *schedule.LintReport `json:"lint,omitempty"`
+	Error    *APIError            `json:"error,omitempty"`
+}
+
+// DownloadResponse struttura risposta download
+type DownloadResponse struct {
+	Success bool   `json:"success"`
+	Message string    `json:"message"`
+	Error   *APIError `json:"error,omitempty"`
+}
+
+// GetConfig ritorna configurazione corrente
+func GetConfig(c *gin.Context) {
+	cfg := config.Get()
+	isConfigured := config.IsConfigured()
+
+	response := ConfigResponse{
+		Configured: isConfigured,
+	}
+
+	if isConfigured {
+		// Non esporre password; credenziali FTP solo agli admin
+		if isAdmin(c) {
+			response.Config = cfg.Masked()
+		} else {
+			response.Config = cfg.Public()
+		}
+	}
+
+	c.JSON(200, response)
+}
+
+// SaveConfig salva configurazione dal setup wizard (identità schermo
+// obbligatoria). Gli altri campi (FTP, tuning player) restano invariati.
+func SaveConfig(c *gin.Context) {
+	var req ConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, "Invalid request")
+		return
+	}
+
+	// Valida campi obbligatori
+	var missing []string
+	if req.Username == "" {
+		missing = append(missing, "username")
+	}
+	if req.Password == "" {
+		missing = append(missing, "password")
+	}
+	if req.IDMonitor == "" {
+		missing = append(missing, "idMonitor")
+	}
+	if len(missing) > 0 {
+		respondError(c, apperr.New(config.CodeMissingFields, "Username, password and idMonitor are required").
+			WithDetail("fields", missing))
+		return
+	}
+
+	patch := config.Patch{
+		Username:    &req.Username,
+		Password:    &req.Password,
+		IDMonitor:   &req.IDMonitor,
+		UserSchermo: &req.UserSchermo,
+	}
+	if req.ServerURL != "" {
+		patch.ServerURL = &req.ServerURL
+	}
+
+	applyConfigPatch(c, &patch)
+}
+
+// PatchConfig aggiorna parzialmente la configurazione (solo campi presenti)
+func PatchConfig(c *gin.Context) {
+	patch, err := config.DecodePatch(c.Request.Body)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	applyConfigPatch(c, patch)
+}
+
+// applyConfigPatch valida, applica e salva patch rispondendo con i campi modificati
+func applyConfigPatch(c *gin.Context, patch *config.Patch) {
+	if errs := patch.Validate(); errs != nil {
+		respondError(c, apperr.New(config.CodeInvalidFields, "Invalid configuration fields").
+			WithDetail("fields", errs))
+		return
+	}
+
+	cfg := config.Get()
+	changed := patch.Apply(cfg)
+
+	// Campi imposti da env o flag non sono modificabili via API
+	overridden := config.FieldErrors{}
+	for _, field := range changed {
+		if origin, ok := config.OverriddenBy(field); ok {
+			overridden[field] = "set by " + origin
+		}
+	}
+	if len(overridden) > 0 {
+		respondError(c, apperr.New(config.CodeInvalidFields, "Configuration fields are overridden").
+			WithDetail("fields", overridden))
+		return
+	}
+
+	// Validazione completa della config risultante (URL, porte, range, directory)
+	if errs := cfg.Validate(); errs != nil {
+		respondError(c, apperr.New(config.CodeInvalidFields, "Invalid configuration fields").
+			WithDetail("fields", errs))
+		return
+	}
+
+	if len(changed) > 0 {
+		if err := config.Save(cfg); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+
+	c.JSON(200, ConfigUpdateResponse{
+		Success: true,
+		Message: "Configuration saved",
+		Changed: changed,
+		Config:  config.Get().Masked(),
+	})
+}
+
+// GetEffectiveConfig ritorna valore effettivo e sorgente di ogni campo
+func GetEffectiveConfig(c *gin.Context) {
+	c.JSON(200, EffectiveConfigResponse{
+		Success: true,
+		Fields:  config.Describe(),
+	})
+}
+
+// ExportConfig esporta la configurazione come bundle di provisioning
+// cifrato e autenticato (server, FTP, template ID monitor, tuning)
+func ExportConfig(c *gin.Context) {
+	var req ExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, "Invalid request")
+		return
+	}
+
+	bundle := config.NewBundle(config.Get(), req.IDMonitorTemplate, req.Monitors)
+	data, err := config.SealBundle(bundle, req.Passphrase)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="spotlive-provision.json"`)
+	c.Data(200, "application/json", data)
+}
+
+// ImportConfig applica un bundle di provisioning alla configurazione
+func ImportConfig(c *gin.Context) {
+	var req ImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Bundle) == 0 {
+		badRequest(c, "Invalid request")
+		return
+	}
+
+	bundle, err := config.OpenBundle(req.Bundle, req.Passphrase)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	serial := strings.TrimSpace(req.Serial)
+	if serial == "" {
+		serial = config.DeviceSerial()
+	}
+
+	cfg := config.Get()
+	bundle.Apply(cfg, serial)
+
+	if errs := cfg.Validate(); errs != nil {
+		respondError(c, apperr.New(config.CodeInvalidFields, "Provisioning bundle has invalid fields").
+			WithDetail("fields", errs))
+		return
+	}
+	if err := config.Save(cfg); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(200, ConfigUpdateResponse{
+		Success: true,
+		Message: "Provisioning bundle applied",
+		Config:  config.Get().Masked(),
+	})
+}
+
+// TestConnection testa connessione al server
+func TestConnection(c *gin.Context) {
+	// Prova a scaricare programmazione
+	schedule, err := xml.FetchSchedule()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "Connection successful",
+		"schermo": schedule.Schermo.Nome,
+		"servlet": xml.Servlet(config.Get()),
+	})
+}
+
+// GetSchedule scarica programmazione dal server
+func GetSchedule(c *gin.Context) {
+	s, err := xml.FetchSchedule()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	lint := schedule.Lint(schedule.FromXML(s))
+	c.JSON(200, ScheduleResponse{
+		Success:  true,
+		Schedule: s,
+		Playlist: s.Playlist(time.Now()),
+		Lint:     &lint,
+	})
+}
+
+// ScheduleV2Response risposta programmazione nel modello normalizzato
+type ScheduleV2Response struct {
+	Success  bool                 `json:"success"`
+	Schedule *schedule.Schedule   `json:"schedule,omitempty"`
+	Lint     *schedule.LintReport `json:"lint,omitempty"`
+	Error    *APIError            `json:"error,omitempty"`
+}
+
+// GetScheduleV2 scarica programmazione e la ritorna normalizzata
+func GetScheduleV2(c *gin.Context) {
+	s, err := xml.FetchSchedule()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	normalized := schedule.FromXML(s)
+	lint := schedule.Lint(normalized)
+	c.JSON(200, ScheduleV2Response{
+		Success:  true,
+		Schedule: normalized,
+		Lint:     &lint,
+	})
+}
+
+// ScheduleLintResponse esito dei controlli semantici
+type ScheduleLintResponse struct {
+	Success bool `json:"success"`
+	schedule.LintReport
+}
+
+// GetScheduleLint scarica programmazione e ne verifica la coerenza
+// (zone sovrapposte o fuori schermo, media senza file, orari invertiti)
+func GetScheduleLint(c *gin.Context) {
+	s, err := xml.FetchSchedule()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(200, ScheduleLintResponse{
+		Success:    true,
+		LintReport: schedule.Lint(schedule.FromXML(s)),
+	})
+}
+
+// ScheduleHistoryResponse versioni recenti della programmazione
+type ScheduleHistoryResponse struct {
+	Success  bool                       `json:"success"`
+	Versions []schedule.RevisionSummary `json:"versions"`
+}
+
+// GetScheduleHistory ritorna le versioni conservate della programmazione
+func GetScheduleHistory(c *gin.Context) {
+	c.JSON(200, ScheduleHistoryResponse{
+		Success:  true,
+		Versions: schedule.History(),
+	})
+}
+
+// ScheduleDiffResponse differenze tra due versioni
+type ScheduleDiffResponse struct {
+	Success bool           `json:"success"`
+	Summary string         `json:"summary"`
+	Diff    *schedule.Diff `json:"diff"`
+}
+
+// GetScheduleDiff confronta due versioni (?from=&to=, default: ultime due)
+func GetScheduleDiff(c *gin.Context) {
+	var ids [2]int
+	for i, name := range []string{"from", "to"} {
+		v := c.Query(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			badRequest(c, "Invalid version: "+name)
+			return
+		}
+		ids[i] = n
+	}
+
+	d, err := schedule.DiffRevisions(ids[0], ids[1])
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(200, ScheduleDiffResponse{
+		Success: true,
+		Summary: d.Summary(),
+		Diff:    d,
+	})
+}
+
+// DownloadMedia scarica file media via FTP proxy
+func DownloadMedia(c *gin.Context) {
+	// Wildcard: il parametro inizia sempre con "/"
+	filename := strings.TrimPrefix(c.Param("filename"), "/")
+	if filename == "" {
+		badRequest(c, "Filename required")
+		return
+	}
+
+	filename, err := cleanMediaPath(filename)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// Path relativo (es: "upload/video.mp4")
+	remotePath := filename
+	if !strings.Contains(filename, "/") {
+		remotePath = "upload/" + filename
+	}
+
+	// Solo media della programmazione o sotto prefissi consentiti
+	if !mediaAllowed(remotePath) {
+		respondError(c, apperr.New(CodeMediaNotAllowed, "Media not allowed").
+			WithDetail("path", remotePath))
+		return
+	}
+
+	// Già nello store locale: evita il proxy FTP
+	if store, err := media.For(config.Get().MediaDir); err == nil {
+		if entry, ok := store.Lookup(remotePath, media.Remote{}); ok {
+			c.Header("Content-Type", contentTypeFor(filename))
+			c.File(store.BlobPath(entry.Hash))
+			return
+		}
+	}
+
+	// Connetti FTP
+	ftpClient := ftp.NewClient()
+	defer ftpClient.Close()
+
+	if err := ftpClient.Connect(); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// Download
+	stream, err := ftpClient.Download(remotePath)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer stream.Close()
+
+	// Stream al client
+	c.Header("Content-Type", contentTypeFor(filename))
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", filepath.Base(filename)))
+	c.Status(200)
+
+	// Copia stream
+	if _, err := io.Copy(c.Writer, stream); err != nil {
+		// Errore già dopo headers inviati, non possiamo più rispondere con JSON
+		fmt.Printf("Error streaming file: %v\n", err)
+	}
+}
+
+// maxUploadBytes dimensione massima file caricabile in cache
+const maxUploadBytes = 512 << 20
+
+// uploadTypes estensioni ammesse in cache → famiglia MIME attesa
+var uploadTypes = map[string]string{
+	".mp4":  "video/",
+	".webm": "video/",
+	".jpg":  "image/",
+	".jpeg": "image/",
+	".png":  "image/",
+	".gif":  "image/",
+}
+
+// checkUploadContent confronta il MIME rilevato dai primi byte con la famiglia attesa
+func checkUploadContent(file *multipart.FileHeader, family string) error {
+	f, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	detected := http.DetectContentType(head[:n])
+	if !strings.HasPrefix(detected, family) {
+		return apperr.New(CodeUnsupportedMedia, "File content does not match its type").
+			WithDetail("detected", detected)
+	}
+	return nil
+}
+
+// contentTypeFor determina content type dall'estensione
+func contentTypeFor(filename string) string {
+	switch filepath.Ext(filename) {
+	case ".mp4":
+		return "video/mp4"
+	case ".webm":
+		return "video/webm"
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".png":
+		return "image/png"
+	case ".gif":
+		return "image/gif"
+	}
+	return "application/octet-stream"
+}
+
+// CacheMedia salva media in cache locale
+func CacheMedia(c *gin.Context) {
+	filename, err := cleanFileName(c.Param("filename"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// Solo tipi media riproducibili
+	family, ok := uploadTypes[strings.ToLower(filepath.Ext(filename))]
+	if !ok {
+		respondError(c, apperr.New(CodeUnsupportedMedia, "Unsupported file type").
+			WithDetail("filename", filename))
+		return
+	}
+
+	cfg := config.Get()
+	cachePath := filepath.Join(cfg.CacheDir, filename)
+
+	// Limita dimensione body (multipart incluso)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadBytes+1<<20)
+
+	// Ricevi file dal client
+	file, err := c.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			respondError(c, apperr.New(CodeUploadTooLarge, "File too large").
+				WithDetail("maxBytes", maxUploadBytes))
+			return
+		}
+		badRequest(c, "File required")
+		return
+	}
+	if file.Size > maxUploadBytes {
+		respondError(c, apperr.New(CodeUploadTooLarge, "File too large").
+			WithDetail("maxBytes", maxUploadBytes))
+		return
+	}
+
+	// Verifica contenuto reale (non solo estensione)
+	if err := checkUploadContent(file, family); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// Salva (non durante un reset della cache)
+	_, done, err := startJob()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer done()
+
+	if err := c.SaveUploadedFile(file, cachePath); err != nil {
+		respondError(c, fmt.Errorf("failed to save file: %w", err))
+		return
+	}
+
+	c.JSON(200, gin.H{"success": true, "path": cachePath})
+}
+
+// GetCachedMedia ritorna media dalla cache
+func GetCachedMedia(c *gin.Context) {
+	filename, err := cleanFileName(c.Param("filename"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	cfg := config.Get()
+	cachePath := filepath.Join(cfg.CacheDir, filename)
+
+	// Verifica esistenza
+	if info, err := os.Stat(cachePath); err != nil || !info.Mode().IsRegular() {
+		respondError(c, apperr.New(apperr.CodeNotFound, "File not found in cache").
+			WithDetail("filename", filename))
+		return
+	}
+
+	c.File(cachePath)
+}
+
+// DownloadAllMedia scarica tutti i media della programmazione
+func DownloadAllMedia(c *gin.Context) {
+	// Interrotto da un eventuale reset
+	ctx, done, err := startJob()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer done()
+
+	// Nessuna sincronizzazione senza credenziali FTP
+	if err := ftp.CheckConfigured(config.Get()); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// Scarica programmazione
+	schedule, err := xml.FetchSchedule()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// Lista files da scaricare
+	files := schedule.GetMediaFiles()
+
+	// Connetti FTP
+	ftpClient := ftp.NewClient()
+	defer ftpClient.Close()
+
+	if err := ftpClient.Connect(); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	cfg := config.Get()
+	store, err := media.For(cfg.MediaDir)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	res, err := syncMedia(ctx, ftpClient, store, files, schedule.MediaSources())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(200, DownloadResponse{
+		Success: true,
+		Message: fmt.Sprintf("Downloaded: %d, Skipped: %d, Deduplicated: %d, Errors: %d", res.Downloaded, res.Skipped, res.Deduplicated, res.Errors),
+	})
+}
+
+// ListRemote elenca ricorsivamente il contenuto del server FTP
+func ListRemote(c *gin.Context) {
+	remotePath := c.DefaultQuery("path", "")
+
+	depth := ftp.DefaultMaxDepth
+	if v := c.Query("depth"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			badRequest(c, "Invalid depth")
+			return
+		}
+		depth = n
+	}
+
+	// Connetti FTP
+	ftpClient := ftp.NewClient()
+	defer ftpClient.Close()
+
+	if err := ftpClient.Connect(); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	listing, err := ftpClient.ListRecursive(remotePath, depth, ftp.DefaultMaxEntries)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"listing": listing,
+	})
+}
+
+// SendHeartbeat invia update al server
+func SendHeartbeat(c *gin.Context) {
+	if err := xml.SendUpdate(); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{"success": true})
+}
+
+// GetStatus ritorna status del player
+func GetStatus(c *gin.Context) {
+	cfg := config.Get()
+
+	// Conta file nello store media
+	var stats media.Stats
+	if store, err := media.For(cfg.MediaDir); err == nil {
+		stats = store.Stats()
+	}
+
+	c.JSON(200, gin.H{
+		"configured":    config.IsConfigured(),
+		"ftpConfigured": ftp.CheckConfigured(cfg) == nil,
+		"idMonitor":     cfg.IDMonitor,
+		"mediaCount":    stats.Files,
+		"mediaBlobs":    stats.Blobs,
+		"mediaBytes":    stats.Bytes,
+		"version":       "6.0.0",
+		"servletHttp":   httpclient.Metrics(),
+	})
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"path"
+	"spotlive-server/internal/apperr"
+	"spotlive-server/internal/config"
+	"spotlive-server/internal/xml"
+	"strings"
+)
+
+// cleanMediaPath valida path remoto relativo (es. "upload/video.mp4").
+// Rifiuta path assoluti, segmenti "."/"..", segmenti vuoti, backslash e
+// caratteri di controllo (NUL, a-capo: iniezione di comandi FTP). "%" e ":"
+// sono ammessi: il CMS li usa nei nomi ("50%.mp4", "ore 10:30.jpg").
+func cleanMediaPath(raw string) (string, error) {
+	invalid := func(reason string) error {
+		return apperr.New(CodeInvalidPath, "Invalid media path").
+			WithDetail("path", raw).
+			WithDetail("reason", reason)
+	}
+
+	if raw == "" {
+		return "", invalid("empty")
+	}
+	if strings.HasPrefix(raw, "/") {
+		return "", invalid("absolute path")
+	}
+	if strings.Contains(raw, "\\") {
+		return "", invalid("forbidden character")
+	}
+	for _, r := range raw {
+		if r < 0x20 || r == 0x7f {
+			return "", invalid("control character")
+		}
+	}
+
+	segments := strings.Split(raw, "/")
+	for _, seg := range segments {
+		switch seg {
+		case "":
+			return "", invalid("empty segment")
+		case ".", "..":
+			return "", invalid("relative segment")
+		}
+	}
+
+	if cleaned := path.Clean(raw); cleaned != raw {
+		return "", invalid("not canonical")
+	}
+	return raw, nil
+}
+
+// cleanFileName valida nome file singolo (nessun separatore di directory)
+func cleanFileName(raw string) (string, error) {
+	name, err := cleanMediaPath(raw)
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(name, "/") {
+		return "", apperr.New(CodeInvalidPath, "Invalid file name").
+			WithDetail("path", raw).
+			WithDetail("reason", "directory separator")
+	}
+	return name, nil
+}
+
+// underPrefix verifica che remotePath sia dentro la directory prefix,
+// confrontando segmenti interi ("upload" non consente "uploads-private/")
+func underPrefix(remotePath, prefix string) bool {
+	prefix = strings.Trim(prefix, "/")
+	return prefix != "" && strings.HasPrefix(remotePath, prefix+"/")
+}
+
+// mediaAllowed verifica che il path sia referenziato dalla programmazione
+// corrente o ricada sotto uno dei prefissi configurati
+func mediaAllowed(remotePath string) bool {
+	for _, prefix := range config.Get().MediaPrefixes {
+		if underPrefix(remotePath, prefix) {
+			return true
+		}
+	}
+
+	if schedule := xml.LastSchedule(); schedule != nil {
+		for _, f := range schedule.GetMediaFiles() {
+			if f == remotePath {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"spotlive-server/internal/config"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCleanMediaPath(t *testing.T) {
+	valid := []string{
+		"video.mp4",
+		"upload/video.mp4",
+		"upload/a/spot 01.jpg",
+		"upload/50%.mp4",
+		"upload/ore 10:30.jpg",
+		"upload/sconto%20estate.png",
+	}
+	for _, p := range valid {
+		if got, err := cleanMediaPath(p); err != nil || got != p {
+			t.Errorf("cleanMediaPath(%q) = %q, %v; want accepted", p, got, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"..",
+		"../config.json",
+		"upload/../../config.json",
+		"upload/./video.mp4",
+		"upload//video.mp4",
+		"upload/",
+		"/etc/passwd",
+		"//etc/passwd",
+		"upload\\..\\config.json",
+		"ftp://host/file",
+		"upload/video.mp4\x00.jpg",
+		"upload/\nvideo.mp4",
+	}
+	for _, p := range invalid {
+		if _, err := cleanMediaPath(p); err == nil {
+			t.Errorf("cleanMediaPath(%q) accepted; want rejected", p)
+		}
+	}
+}
+
+func TestCleanFileName(t *testing.T) {
+	if _, err := cleanFileName("video.mp4"); err != nil {
+		t.Errorf("cleanFileName(video.mp4) = %v", err)
+	}
+	for _, p := range []string{"upload/video.mp4", "../video.mp4", "/video.mp4", ""} {
+		if _, err := cleanFileName(p); err == nil {
+			t.Errorf("cleanFileName(%q) accepted; want rejected", p)
+		}
+	}
+}
+
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/media/*filename", DownloadMedia)
+	r.GET("/api/cache/:filename", GetCachedMedia)
+	r.POST("/api/cache/:filename", CacheMedia)
+	return r
+}
+
+func TestDownloadMediaRejectsTraversal(t *testing.T) {
+	r := newTestRouter()
+
+	cases := map[string]int{
+		"/api/media/upload/../../config.json":       http.StatusBadRequest,
+		"/api/media/upload%2F..%2F..%2Fconfig.json": http.StatusBadRequest,
+		"/api/media//etc/passwd":                    http.StatusBadRequest,
+		"/api/media/upload%5C..%5Cconfig.json":      http.StatusBadRequest,
+		"/api/media/private/video.mp4":              http.StatusForbidden,
+		"/api/media/private%2Fvideo.mp4":            http.StatusForbidden,
+	}
+	for target, want := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != want {
+			t.Errorf("GET %s = %d; want %d (%s)", target, w.Code, want, w.Body.String())
+		}
+	}
+}
+
+// rejected verifica rifiuto dal handler (400) o dal router (404, slash decodificati)
+func rejected(code int) bool {
+	return code == http.StatusBadRequest || code == http.StatusNotFound
+}
+
+func TestCachedMediaRejectsTraversal(t *testing.T) {
+	r := newTestRouter()
+
+	for _, target := range []string{
+		"/api/cache/..%2Fconfig.json",
+		"/api/cache/%2Fetc%2Fpasswd",
+		"/api/cache/..",
+		"/api/cache/%2e%2e",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if !rejected(w.Code) {
+			t.Errorf("GET %s = %d; want 400/404", target, w.Code)
+		}
+	}
+}
+
+func TestCacheMediaRejectsBadUploads(t *testing.T) {
+	r := newTestRouter()
+
+	upload := func(target string, content []byte) int {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, _ := mw.CreateFormFile("file", "upload")
+		fw.Write(content)
+		mw.Close()
+
+		req := httptest.NewRequest(http.MethodPost, target, &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := upload("/api/cache/..%2Fconfig.json", []byte("x")); !rejected(code) {
+		t.Errorf("traversal upload = %d; want 400/404", code)
+	}
+	if code := upload("/api/cache/..", []byte("x")); code != http.StatusBadRequest {
+		t.Errorf("dot-dot upload = %d; want 400", code)
+	}
+	if code := upload("/api/cache/script.sh", []byte("#!/bin/sh")); code != http.StatusUnsupportedMediaType {
+		t.Errorf("script upload = %d; want 415", code)
+	}
+	if code := upload("/api/cache/fake.png", []byte("<html>not an image</html>")); code != http.StatusUnsupportedMediaType {
+		t.Errorf("disguised upload = %d; want 415", code)
+	}
+}
+
+func TestMediaAllowedMatchesWholeSegments(t *testing.T) {
+	config.SetDataDir(t.TempDir())
+	if _, err := config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { config.SetOverlay(nil, nil) })
+
+	for _, prefixes := range []string{"upload", "upload/", "/upload/"} {
+		if err := config.SetOverlay(nil, map[string]string{"mediaPrefixes": prefixes}); err != nil {
+			t.Fatal(err)
+		}
+		cases := map[string]bool{
+			"upload/video.mp4":          true,
+			"upload/sub/video.mp4":      true,
+			"uploads-private/video.mp4": false,
+			"upload-old/video.mp4":      false,
+			"upload":                    false,
+			"private/upload/video.mp4":  false,
+		}
+		for path, want := range cases {
+			if got := mediaAllowed(path); got != want {
+				t.Errorf("prefix %q: mediaAllowed(%q) = %v, want %v", prefixes, path, got, want)
+			}
+		}
+	}
+}
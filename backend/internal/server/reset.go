@@ -0,0 +1,232 @@
+package server
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"spotlive-server/internal/apperr"
+	"spotlive-server/internal/config"
+	"spotlive-server/internal/media"
+	"spotlive-server/internal/schedule"
+	"spotlive-server/internal/xml"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Modalità di reset
+const (
+	ResetConfig = "config" // solo configurazione
+	ResetMedia  = "media"  // solo media e cache
+	ResetAll    = "all"    // tutto: torna allo stato di setup
+)
+
+// Job in corso (download, upload): il reset li interrompe e attende
+// che terminino prima di cancellare i file
+var (
+	jobsMu     sync.Mutex
+	jobsCtx    context.Context
+	jobsCancel context.CancelFunc
+	jobsWG     sync.WaitGroup
+	resetting  bool
+)
+
+func init() {
+	jobsCtx, jobsCancel = context.WithCancel(context.Background())
+}
+
+// startJob registra un job; ctx viene annullato da un reset. Chiamare
+// done al termine. Rifiuta nuovi job durante un reset.
+func startJob() (ctx context.Context, done func(), err error) {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+
+	if resetting {
+		return nil, nil, apperr.New(CodeResetInProgress, "Reset in progress").AsRetryable()
+	}
+	jobsWG.Add(1)
+	return jobsCtx, jobsWG.Done, nil
+}
+
+// stopJobs annulla i job in corso e attende che terminino
+func stopJobs() error {
+	jobsMu.Lock()
+	if resetting {
+		jobsMu.Unlock()
+		return apperr.New(CodeResetInProgress, "Reset in progress").AsRetryable()
+	}
+	resetting = true
+	jobsCancel()
+	jobsMu.Unlock()
+
+	jobsWG.Wait()
+	return nil
+}
+
+// resumeJobs riabilita i job dopo il reset
+func resumeJobs() {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+
+	jobsCtx, jobsCancel = context.WithCancel(context.Background())
+	resetting = false
+}
+
+// jobReader interrompe la lettura quando il job viene annullato
+type jobReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (j jobReader) Read(p []byte) (int, error) {
+	if err := j.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return j.r.Read(p)
+}
+
+// ResetRequest modalità di reset richiesta
+type ResetRequest struct {
+	Mode string `json:"mode"`
+}
+
+// ResetResponse esito del reset
+type ResetResponse struct {
+	Success bool     `json:"success"`
+	Mode    string   `json:"mode"`
+	Removed []string `json:"removed"`
+}
+
+// ResetDevice cancella configurazione e/o media per riutilizzare il box.
+// Richiede sempre il token admin (anche da loopback).
+func ResetDevice(c *gin.Context) {
+	var req ResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, "Invalid request")
+		return
+	}
+	switch req.Mode {
+	case ResetConfig, ResetMedia, ResetAll:
+	default:
+		respondError(c, apperr.New(apperr.CodeInvalidRequest, "Invalid reset mode").
+			WithDetail("mode", req.Mode).
+			WithDetail("allowed", []string{ResetConfig, ResetMedia, ResetAll}))
+		return
+	}
+
+	if err := stopJobs(); err != nil {
+		respondError(c, err)
+		return
+	}
+	defer resumeJobs()
+
+	// Nessuna rilettura della config né registrazione di programmazioni
+	// (scaricate con la config precedente) mentre i file vengono cancellati
+	defer config.HoldReload()()
+	defer schedule.PauseTracking()()
+
+	// Directory lette prima del reset config (che le riporta ai default)
+	cfg := config.Get()
+	removed := []string{}
+
+	if req.Mode == ResetMedia || req.Mode == ResetAll {
+		for _, dir := range []string{cfg.MediaDir, cfg.CacheDir} {
+			if err := checkWipeDir(dir, cfg.DataDir); err != nil {
+				respondError(c, err)
+				return
+			}
+		}
+
+		store, err := media.For(cfg.MediaDir)
+		if err == nil {
+			err = store.Wipe()
+		}
+		if err != nil {
+			respondError(c, apperr.Wrap(err, apperr.CodeInternal, "Media wipe failed"))
+			return
+		}
+		removed = append(removed, cfg.MediaDir)
+
+		if err := wipeDir(cfg.CacheDir, cfg.DataDir); err != nil {
+			respondError(c, apperr.Wrap(err, apperr.CodeInternal, "Cache wipe failed").
+				WithDetail("dir", cfg.CacheDir))
+			return
+		}
+		removed = append(removed, cfg.CacheDir)
+	}
+
+	if req.Mode == ResetConfig || req.Mode == ResetAll {
+		files, err := config.Reset()
+		removed = append(removed, files...)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		xml.ResetLastSchedule()
+		schedule.ResetHistory()
+		xml.ResetCapabilities()
+	}
+
+	c.JSON(200, ResetResponse{
+		Success: true,
+		Mode:    req.Mode,
+		Removed: removed,
+	})
+}
+
+// checkWipeDir consente il reset solo di directory interne alla data dir
+// configurata (dataDir, vuota = quella del processo): mediaDir e cacheDir
+// possono essere impostate separatamente da env o flag
+func checkWipeDir(dir, dataDir string) error {
+	if dir == "" {
+		return nil
+	}
+	root := dataDir
+	if root == "" {
+		root = config.DataRoot()
+	}
+	if root == "" {
+		return apperr.New(apperr.CodeInvalidRequest, "data directory not set")
+	}
+	// Data dir radice del filesystem: tutto sarebbe "interno"
+	if clean := filepath.Clean(root); !filepath.IsAbs(clean) || filepath.Dir(clean) == clean {
+		return apperr.New(apperr.CodeInvalidRequest, "refusing to wipe with data directory at filesystem root").
+			WithDetail("dataDir", root)
+	}
+
+	rel, err := filepath.Rel(filepath.Clean(root), filepath.Clean(dir))
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return apperr.New(apperr.CodeInvalidRequest, "refusing to wipe directory outside data directory").
+			WithDetail("dir", dir).
+			WithDetail("dataDir", root)
+	}
+	return nil
+}
+
+// wipeDir elimina il contenuto di dir lasciando la directory. Rifiuta
+// path vuoti o esterni alla data dir (vedi checkWipeDir).
+func wipeDir(dir, dataDir string) error {
+	if dir == "" {
+		return nil
+	}
+	if err := checkWipeDir(dir, dataDir); err != nil {
+		return err
+	}
+	dir = filepath.Clean(dir)
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"spotlive-server/internal/config"
+	"testing"
+)
+
+func TestWipeDirOnlyInsideDataDir(t *testing.T) {
+	root := t.TempDir()
+	config.SetDataDir(root)
+
+	outside := t.TempDir()
+	keep := filepath.Join(outside, "keep.txt")
+	if err := os.WriteFile(keep, []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{outside, root, filepath.Join(root, "..", filepath.Base(outside)), "/"} {
+		if err := wipeDir(dir, ""); err == nil {
+			t.Errorf("wipeDir(%q) allowed", dir)
+		}
+	}
+	if _, err := os.Stat(keep); err != nil {
+		t.Fatalf("file outside data dir removed: %v", err)
+	}
+
+	cache := filepath.Join(root, "cache")
+	os.MkdirAll(cache, 0755)
+	os.WriteFile(filepath.Join(cache, "old.jpg"), []byte("x"), 0600)
+	if err := wipeDir(cache, ""); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(cache); len(entries) != 0 {
+		t.Errorf("cache not wiped: %v", entries)
+	}
+}
+
+func TestWipeDirUsesConfiguredDataDir(t *testing.T) {
+	config.SetDataDir(t.TempDir())
+
+	// dataDir spostata via API (es. scheda SD): cache interna alla nuova dir
+	moved := t.TempDir()
+	cache := filepath.Join(moved, "cache")
+	os.MkdirAll(cache, 0755)
+	os.WriteFile(filepath.Join(cache, "old.jpg"), []byte("x"), 0600)
+	if err := wipeDir(cache, moved); err != nil {
+		t.Fatalf("wipe inside configured dataDir: %v", err)
+	}
+	if entries, _ := os.ReadDir(cache); len(entries) != 0 {
+		t.Errorf("cache not wiped: %v", entries)
+	}
+
+	// cacheDir da env fuori dalla dataDir configurata
+	outside := t.TempDir()
+	if err := wipeDir(outside, moved); err == nil {
+		t.Error("wipe outside configured dataDir allowed")
+	}
+
+	// dataDir alla radice: nessuna cancellazione
+	if err := checkWipeDir(filepath.Join(string(filepath.Separator), "media"), string(filepath.Separator)); err == nil {
+		t.Error("wipe with dataDir at filesystem root allowed")
+	}
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"spotlive-server/internal/apperr"
+	"spotlive-server/internal/ftp"
+	"spotlive-server/internal/media"
+)
+
+// mediaSource sorgente remota dei media (client FTP)
+type mediaSource interface {
+	Stat(remotePath string) (*ftp.Entry, error)
+	Download(remotePath string) (io.ReadCloser, error)
+}
+
+// syncResult conteggi di una sincronizzazione media
+type syncResult struct {
+	Downloaded   int
+	Skipped      int
+	Deduplicated int
+	Errors       int
+}
+
+// syncMedia scarica nello store i file mancanti o modificati. sources
+// (path → identità CMS) riconosce i file rinominati sul server, che
+// vengono associati al blob esistente senza scaricarli di nuovo.
+func syncMedia(ctx context.Context, src mediaSource, store *media.Store, files []string, sources map[string]string) (syncResult, error) {
+	var res syncResult
+
+	for _, filePathRel := range files {
+		if ctx.Err() != nil {
+			return res, apperr.New(CodeResetInProgress, "Download interrupted by reset").
+				WithDetail("downloaded", res.Downloaded)
+		}
+
+		// Path forniti dal CMS: scarta quelli non sicuri
+		if _, err := cleanMediaPath(filePathRel); err != nil {
+			fmt.Printf("Skipping unsafe media path %q\n", filePathRel)
+			res.Errors++
+			continue
+		}
+
+		// Dimensione e data remote: rilevano contenuto sostituito sul server
+		remote := media.Remote{Source: sources[filePathRel]}
+		if st, err := src.Stat(filePathRel); err == nil {
+			remote.Size = int64(st.Size)
+			remote.ModTime = st.ModTime
+		}
+
+		// Salta se già presente e invariato nello store
+		if _, ok := store.Lookup(filePathRel, remote); ok {
+			res.Skipped++
+			continue
+		}
+
+		// Stesso media CMS sotto un altro path: file rinominato sul server
+		if e, ok := store.FindBySource(remote); ok {
+			if err := store.Link(filePathRel, e, remote); err != nil {
+				fmt.Printf("Error storing %s: %v\n", filePathRel, err)
+				res.Errors++
+				continue
+			}
+			res.Deduplicated++
+			continue
+		}
+
+		// Download
+		stream, err := src.Download(filePathRel)
+		if err != nil {
+			fmt.Printf("Error downloading %s: %v\n", filePathRel, err)
+			res.Errors++
+			continue
+		}
+
+		// Salva nello store (hash del contenuto)
+		_, dedup, err := store.Put(filePathRel, jobReader{ctx, stream}, remote)
+		stream.Close()
+
+		if err != nil {
+			fmt.Printf("Error storing %s: %v\n", filePathRel, err)
+			res.Errors++
+			continue
+		}
+
+		if dedup {
+			res.Deduplicated++
+		} else {
+			res.Downloaded++
+		}
+	}
+
+	return res, nil
+}
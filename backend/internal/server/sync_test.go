@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"io"
+	"os"
+	"spotlive-server/internal/ftp"
+	"spotlive-server/internal/media"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSource server FTP in memoria che conta i download
+type fakeSource struct {
+	files     map[string]string
+	modTime   time.Time
+	downloads []string
+}
+
+func (f *fakeSource) Stat(remotePath string) (*ftp.Entry, error) {
+	data, ok := f.files[remotePath]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return &ftp.Entry{Path: remotePath, Type: "file", Size: uint64(len(data)), ModTime: f.modTime}, nil
+}
+
+func (f *fakeSource) Download(remotePath string) (io.ReadCloser, error) {
+	data, ok := f.files[remotePath]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	f.downloads = append(f.downloads, remotePath)
+	return io.NopCloser(strings.NewReader(data)), nil
+}
+
+func TestSyncMediaRenamedFileNotDownloaded(t *testing.T) {
+	store, err := media.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &fakeSource{
+		files:   map[string]string{"upload/spot.mp4": "video A"},
+		modTime: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+	}
+	sources := map[string]string{"upload/spot.mp4": "media:42/video"}
+
+	res, err := syncMedia(context.Background(), src, store, []string{"upload/spot.mp4"}, sources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Downloaded != 1 || len(src.downloads) != 1 {
+		t.Fatalf("first sync: %+v, downloads %v", res, src.downloads)
+	}
+
+	// Il CMS rinomina il file: stesso media, dimensione e data
+	src.files = map[string]string{"upload/spot-renamed.mp4": "video A"}
+	sources = map[string]string{"upload/spot-renamed.mp4": "media:42/video"}
+	src.downloads = nil
+
+	res, err = syncMedia(context.Background(), src, store, []string{"upload/spot-renamed.mp4"}, sources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(src.downloads) != 0 {
+		t.Errorf("renamed file downloaded again: %v", src.downloads)
+	}
+	if res.Deduplicated != 1 {
+		t.Errorf("result = %+v, want 1 deduplicated", res)
+	}
+	if _, ok := store.Lookup("upload/spot-renamed.mp4", media.Remote{}); !ok {
+		t.Error("renamed path not in store")
+	}
+}
+
+func TestSyncMediaDifferentMediaDownloaded(t *testing.T) {
+	store, err := media.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &fakeSource{
+		files: map[string]string{
+			"upload/a.mp4": "video A",
+			"upload/b.mp4": "video B",
+		},
+		modTime: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+	}
+	sources := map[string]string{
+		"upload/a.mp4": "media:1/video",
+		"upload/b.mp4": "media:2/video",
+	}
+
+	// Stessa dimensione e data ma media CMS diverso: nessun collegamento
+	res, err := syncMedia(context.Background(), src, store, []string{"upload/a.mp4", "upload/b.mp4"}, sources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Downloaded != 2 || len(src.downloads) != 2 {
+		t.Errorf("result = %+v, downloads %v", res, src.downloads)
+	}
+}
@@ -0,0 +1,39 @@
+package xml
+
+import (
+	"net/http"
+	"spotlive-server/internal/apperr"
+)
+
+// Codici errore servlet / programmazione
+const (
+	CodeInvalidServerURL  apperr.Code = "SERVER_URL_INVALID"
+	CodeServerUnreachable apperr.Code = "SERVER_UNREACHABLE"
+	CodeServerAuthFailed  apperr.Code = "SERVER_AUTH_FAILED"
+	CodeServerHTTPError   apperr.Code = "SERVER_HTTP_ERROR"
+	CodeScheduleRead      apperr.Code = "SCHEDULE_READ_FAILED"
+	CodeScheduleParse     apperr.Code = "SCHEDULE_PARSE_FAILED"
+	CodeScheduleTooLarge  apperr.Code = "SCHEDULE_TOO_LARGE"
+)
+
+// tooLargeError risposta oltre il limite maxScheduleBytes
+func tooLargeError(limit int64) *apperr.Error {
+	return apperr.New(CodeScheduleTooLarge, "schedule response exceeds size limit").
+		WithDetail("maxScheduleBytes", limit)
+}
+
+// httpStatusError converte status HTTP non-200 in errore tipizzato
+func httpStatusError(status int) *apperr.Error {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return apperr.New(CodeServerAuthFailed, "server rejected credentials").
+			WithDetail("status", status)
+	case status >= 500:
+		return apperr.New(CodeServerHTTPError, "server error").
+			WithDetail("status", status).
+			AsRetryable()
+	default:
+		return apperr.New(CodeServerHTTPError, "unexpected HTTP status").
+			WithDetail("status", status)
+	}
+}
@@ -0,0 +1,43 @@
+package xml
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"spotlive-server/internal/apperr"
+	"testing"
+)
+
+// FuzzParse: nessun input deve causare panic; un parse riuscito deve
+// sempre essere serializzabile (risposta di /api/schedule).
+//
+//	go test ./internal/xml -fuzz FuzzParse -fuzztime 60s
+func FuzzParse(f *testing.F) {
+	fixtures, _ := filepath.Glob("testdata/*.xml")
+	for _, path := range fixtures {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Add([]byte(""))
+	f.Add([]byte("<it.zerounorabbit.spotlivescreen.SchermoXml/>"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s, err := Parse(data)
+		if err != nil {
+			if code := apperr.CodeOf(err); code != CodeScheduleParse {
+				t.Fatalf("parse error code = %s; want %s", code, CodeScheduleParse)
+			}
+			return
+		}
+
+		if _, err := json.Marshal(s); err != nil {
+			t.Fatalf("parsed schedule not serializable: %v", err)
+		}
+		s.Playlist(goldenTime)
+		s.MediaFilesAt(goldenTime)
+		s.GetDownloadSchedule()
+	})
+}
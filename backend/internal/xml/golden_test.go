@@ -0,0 +1,101 @@
+package xml
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"spotlive-server/internal/apperr"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Rigenera i golden dopo una modifica voluta del modello:
+//
+//	go test ./internal/xml -run TestGolden -update
+var update = flag.Bool("update", false, "rewrite golden files in testdata/golden")
+
+// goldenTime istante di riferimento per playlist e media (campagne)
+var goldenTime = time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+func TestMain(m *testing.M) {
+	// Date senza fuso interpretate in UTC: golden indipendenti dalla macchina
+	time.Local = time.UTC
+	os.Exit(m.Run())
+}
+
+// goldenOutput risultato del parse confrontato con testdata/golden/<nome>.json
+type goldenOutput struct {
+	Schedule   *SchermoXml  `json:"schedule,omitempty"`
+	Playlist   []Int        `json:"playlist,omitempty"`
+	MediaFiles []string     `json:"mediaFiles,omitempty"`
+	Downloads  []string     `json:"downloads,omitempty"`
+	Error      *goldenError `json:"error,omitempty"`
+}
+
+type goldenError struct {
+	Code    apperr.Code `json:"code"`
+	Message string      `json:"message"`
+}
+
+func goldenJSON(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var out goldenOutput
+	s, err := Parse(data)
+	if err != nil {
+		out.Error = &goldenError{Code: apperr.CodeOf(err), Message: err.Error()}
+	} else {
+		out.Schedule = s
+		for _, mf := range s.Playlist(goldenTime) {
+			out.Playlist = append(out.Playlist, mf.Media.ID)
+		}
+		out.MediaFiles = s.MediaFilesAt(goldenTime)
+		out.Downloads = s.GetDownloadSchedule()
+	}
+
+	got, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return append(got, '\n')
+}
+
+func TestGolden(t *testing.T) {
+	fixtures, err := filepath.Glob("testdata/*.xml")
+	if err != nil || len(fixtures) == 0 {
+		t.Fatalf("no fixtures: %v", err)
+	}
+
+	for _, path := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(path), ".xml")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := goldenJSON(t, data)
+			goldenPath := filepath.Join("testdata", "golden", name+".json")
+
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(goldenPath), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(goldenPath, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("golden missing (run with -update): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s differs from golden; if intended run with -update\n got:\n%s", goldenPath, got)
+			}
+		})
+	}
+}
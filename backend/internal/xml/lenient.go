@@ -0,0 +1,222 @@
+package xml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Decodifica tollerante: il servlet (XStream) può emettere ISO-8859-1,
+// booleani come 1/0, orari come 8:00. Un valore non interpretabile non fa
+// fallire la programmazione: il campo resta al valore zero e viene
+// registrato un Warning.
+
+// Warning valore di un campo non interpretabile (campo lasciato a zero)
+type Warning struct {
+	Field   string `json:"field"`
+	Value   string `json:"value"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// warnSink warning raccolti durante una decodifica
+type warnSink struct {
+	warnings []Warning
+	line     func() int // riga dell'elemento in decodifica
+}
+
+// warnSinks raccoglitori di warning per decoder attivo
+var warnSinks sync.Map // *xml.Decoder → *warnSink
+
+// warn registra warning per il decoder (ignorato fuori da Parse)
+func warn(d *xml.Decoder, start xml.StartElement, value, msg string) {
+	v, ok := warnSinks.Load(d)
+	if !ok {
+		return
+	}
+	sink := v.(*warnSink)
+	sink.warnings = append(sink.warnings, Warning{
+		Field:   start.Name.Local,
+		Value:   value,
+		Line:    sink.line(),
+		Message: msg,
+	})
+}
+
+// decodeLenient decodifica in v raccogliendo i warning dei campi; line
+// ritorna la riga dell'elemento in decodifica
+func decodeLenient(d *xml.Decoder, line func() int, v interface{}) ([]Warning, error) {
+	sink := &warnSink{warnings: []Warning{}, line: line}
+	warnSinks.Store(d, sink)
+	defer warnSinks.Delete(d)
+
+	if err := d.Decode(v); err != nil {
+		return sink.warnings, err
+	}
+	return sink.warnings, nil
+}
+
+// charsetReader converte in UTF-8 le codifiche usate dalle JVM del servlet
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	case "iso-8859-1", "iso8859-1", "latin1", "latin-1", "l1":
+		return &singleByteReader{r: input}, nil
+	case "iso-8859-15", "iso8859-15", "latin9", "latin-9":
+		return &singleByteReader{r: input, latin9: true}, nil
+	case "windows-1252", "cp1252":
+		return &singleByteReader{r: input, table: &cp1252}, nil
+	}
+	return nil, fmt.Errorf("unsupported charset %q", charset)
+}
+
+// singleByteReader decodifica charset a singolo byte in UTF-8. Senza
+// table è ISO-8859-1 (byte = code point).
+type singleByteReader struct {
+	r      io.Reader
+	table  *[32]rune // 0x80-0x9F (windows-1252)
+	latin9 bool      // ISO-8859-15: 8 caratteri diversi da ISO-8859-1
+	buf    []byte
+	in     [512]byte
+}
+
+func (s *singleByteReader) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 {
+		n, err := s.r.Read(s.in[:])
+		for _, b := range s.in[:n] {
+			r := rune(b)
+			if s.table != nil && b >= 0x80 && b <= 0x9F {
+				r = s.table[b-0x80]
+			}
+			if s.latin9 {
+				if l9, ok := latin9[b]; ok {
+					r = l9
+				}
+			}
+			s.buf = utf8.AppendRune(s.buf, r)
+		}
+		if err != nil && len(s.buf) == 0 {
+			return 0, err
+		}
+		if n == 0 && err == nil {
+			continue
+		}
+		break
+	}
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+// cp1252 caratteri windows-1252 nell'intervallo 0x80-0x9F
+var cp1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '\u008d', 'Ž', '\u008f',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '\u009d', 'ž', 'Ÿ',
+}
+
+// latin9 caratteri ISO-8859-15 diversi da ISO-8859-1
+var latin9 = map[byte]rune{
+	0xA4: '€', 0xA6: 'Š', 0xA8: 'š', 0xB4: 'Ž',
+	0xB8: 'ž', 0xBC: 'Œ', 0xBD: 'œ', 0xBE: 'Ÿ',
+}
+
+// Bool booleano tollerante: true/false, 1/0, yes/no, si/no (vuoto = false)
+type Bool bool
+
+// UnmarshalXML decodifica booleano, warning se non riconosciuto
+func (b *Bool) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "true", "1", "yes", "si", "sì", "y", "s":
+		*b = true
+	case "false", "0", "no", "n", "":
+		*b = false
+	default:
+		*b = false
+		warn(d, start, s, "invalid boolean")
+	}
+	return nil
+}
+
+// Int intero tollerante: spazi, vuoto = 0, decimali interi ("15.0")
+type Int int
+
+// UnmarshalXML decodifica intero, warning se non numerico
+func (i *Int) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+	v := strings.TrimSpace(s)
+	if v == "" {
+		*i = 0
+		return nil
+	}
+	if n, err := strconv.Atoi(v); err == nil {
+		*i = Int(n)
+		return nil
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil && f == math.Trunc(f) && math.Abs(f) <= math.MaxInt32 {
+		*i = Int(f)
+		return nil
+	}
+	*i = 0
+	warn(d, start, s, "invalid integer")
+	return nil
+}
+
+// Ora orario del giorno normalizzato a "HH:MM:SS" (accetta 8:00, 08:00,
+// 08:00:00, 8.00, 08:00:00.0). Vuoto se assente o non valido.
+type Ora string
+
+// ParseOra normalizza un orario ("" se vuoto)
+func ParseOra(value string) (Ora, error) {
+	v := strings.TrimSpace(value)
+	if v == "" {
+		return "", nil
+	}
+	v = strings.ReplaceAll(v, ".", ":")
+
+	parts := strings.Split(v, ":")
+	if len(parts) == 4 {
+		// Frazione di secondo (08:00:00.0)
+		parts = parts[:3]
+	}
+	if len(parts) < 2 || len(parts) > 3 {
+		return "", fmt.Errorf("invalid time %q", value)
+	}
+
+	limits := []int{23, 59, 59}
+	fields := []int{0, 0, 0}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 || n > limits[i] || len(p) > 2 {
+			return "", fmt.Errorf("invalid time %q", value)
+		}
+		fields[i] = n
+	}
+	return Ora(fmt.Sprintf("%02d:%02d:%02d", fields[0], fields[1], fields[2])), nil
+}
+
+// UnmarshalXML decodifica orario, warning se non valido
+func (o *Ora) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+	parsed, err := ParseOra(s)
+	if err != nil {
+		warn(d, start, s, "invalid time")
+	}
+	*o = parsed
+	return nil
+}
@@ -0,0 +1,29 @@
+package xml
+
+import "testing"
+
+func TestCharsetLatin9(t *testing.T) {
+	data := []byte("<?xml version=\"1.0\" encoding=\"ISO-8859-15\"?>" +
+		"<it.zerounorabbit.spotlivescreen.SchermoXml><schermo>" +
+		"<nome>Offerta 5\xa4 \xbc\xbd</nome>" +
+		"</schermo></it.zerounorabbit.spotlivescreen.SchermoXml>")
+
+	s, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Offerta 5€ Œœ"; s.Schermo.Nome != want {
+		t.Errorf("nome = %q; want %q", s.Schermo.Nome, want)
+	}
+
+	// ISO-8859-1: stesso byte è il simbolo di valuta generico
+	latin1 := []byte("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>" +
+		"<it.zerounorabbit.spotlivescreen.SchermoXml><schermo><nome>\xa4</nome></schermo></it.zerounorabbit.spotlivescreen.SchermoXml>")
+	s, err = Parse(latin1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Schermo.Nome != "¤" {
+		t.Errorf("latin1 nome = %q; want ¤", s.Schermo.Nome)
+	}
+}
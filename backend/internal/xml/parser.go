@@ -1,224 +1,469 @@
-package xml
-
-import (
-	"encoding/xml"
-	"fmt"
-	"io"
-	"net/http"
-	"net/url"
-	"spotlive-server/internal/config"
-	"time"
-)
-
-// SchermoXml root structure
-type SchermoXml struct {
-	XMLName          xml.Name          `xml:"it.zerounorabbit.spotlivescreen.SchermoXml"`
-	Schermo          Schermo           `xml:"schermo"`
-	MediaFinestre    []MediaFinestra   `xml:"mediaFinestre>it.zerounorabbit.spotlivescreen.MediaFinestra"`
-	Programmazioni   interface{}       `xml:"programmazioni"`
-	Media            []Media           `xml:"media>it.zerounorabbit.spotlivescreen.Media"`
-}
-
-// Schermo configurazione display
-type Schermo struct {
-	ID                      int                `xml:"id"`
-	Nome                    string             `xml:"nome"`
-	Indirizzo               string             `xml:"indirizzo"`
-	Larghezza               int                `xml:"larghezza"`
-	Altezza                 int                `xml:"altezza"`
-	Attivo                  bool               `xml:"attivo"`
-	Finestre                []Finestra         `xml:"finestre>it.zerounorabbit.spotlivescreen.Finestra"`
-	Orari                   []Orario           `xml:"orari>it.zerounorabbit.spotlivescreen.Orario"`
-	CategoriaMerceologica   Categoria          `xml:"categoriaMerceologica"`
-	Lun                     bool               `xml:"lun"`
-	Mar                     bool               `xml:"mar"`
-	Mer                     bool               `xml:"mer"`
-	Gio                     bool               `xml:"gio"`
-	Ven                     bool               `xml:"ven"`
-	Sab                     bool               `xml:"sab"`
-	Dom                     bool               `xml:"dom"`
-	Elenco                  bool               `xml:"elenco"`
-	OraDownload01           string             `xml:"oraDownload01"`
-	OraDownload02           string             `xml:"oraDownload02"`
-	OraDownload03           string             `xml:"oraDownload03"`
-	OraDownload04           string             `xml:"oraDownload04"`
-	OraDownload05           string             `xml:"oraDownload05"`
-	OraDownload06           string             `xml:"oraDownload06"`
-	OraDownload07           string             `xml:"oraDownload07"`
-	OraDownload08           string             `xml:"oraDownload08"`
-	OraRestart              string             `xml:"oraRestart"`
-}
-
-// Finestra zona dello schermo
-type Finestra struct {
-	ID              int    `xml:"id"`
-	Nome            string `xml:"nome"`
-	Altezza         int    `xml:"altezza"`
-	Larghezza       int    `xml:"larghezza"`
-	Alto            int    `xml:"alto"`
-	Destra          int    `xml:"destra"`
-	Attiva          bool   `xml:"attiva"`
-	Spot            bool   `xml:"spot"`
-	ImgNoInternet   string `xml:"imgNoInternet"`
-	Audio           bool   `xml:"audio"`
-}
-
-// Orario fascia oraria
-type Orario struct {
-	ID         int    `xml:"id"`
-	OraInizio  string `xml:"oraInizio"`
-	OraFine    string `xml:"oraFine"`
-	Crediti    int    `xml:"crediti"`
-}
-
-// MediaFinestra associazione media-finestra
-type MediaFinestra struct {
-	ID        int    `xml:"id"`
-	Tipo      string `xml:"tipo"`
-	Ordine    int    `xml:"ordine"`
-	Media     Media  `xml:"media"`
-}
-
-// Media contenuto multimediale
-type Media struct {
-	ID             int       `xml:"id"`
-	Nome           string    `xml:"nome"`
-	Attivo         bool      `xml:"attivo"`
-	Tipo           string    `xml:"tipo"`
-	Video          string    `xml:"video"`
-	Immagine       string    `xml:"immagine"`
-	Audio          string    `xml:"audio"`
-	Miniatura      string    `xml:"miniatura"`
-	Pubblico       bool      `xml:"pubblico"`
-	Tempo          int       `xml:"tempo"`
-	NumeroNotizie  int       `xml:"numeroNotizie"`
-	Approvato      bool      `xml:"approvato"`
-	Crediti        int       `xml:"crediti"`
-	Categoria      Categoria `xml:"categoria"`
-}
-
-// Categoria categoria merceologica
-type Categoria struct {
-	ID   int    `xml:"id"`
-	Nome string `xml:"nome"`
-}
-
-// FetchSchedule scarica programmazione dal server
-func FetchSchedule() (*SchermoXml, error) {
-	cfg := config.Get()
-
-	// Costruisce URL (identico al vecchio client)
-	baseURL := cfg.ServerURL + "/spotlivescreen/XmlServlet"
-	params := url.Values{}
-	params.Add("version", "600")
-	params.Add("idSchermo", cfg.IDMonitor)
-	if cfg.UserSchermo != "" {
-		params.Add("userschermo", cfg.UserSchermo)
-	}
-
-	fullURL := baseURL + "?" + params.Encode()
-
-	// HTTP GET
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := http.Get(fullURL)
-	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
-	}
-
-	// Legge body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("read body failed: %w", err)
-	}
-
-	// Parse XML
-	var schedule SchermoXml
-	if err := xml.Unmarshal(body, &schedule); err != nil {
-		return nil, fmt.Errorf("XML parse failed: %w", err)
-	}
-
-	return &schedule, nil
-}
-
-// SendUpdate invia heartbeat al server
-func SendUpdate() error {
-	cfg := config.Get()
-
-	baseURL := cfg.ServerURL + "/spotlivescreen/XmlServlet"
-	params := url.Values{}
-	params.Add("version", "600")
-	params.Add("update", "1")
-	params.Add("idSchermo", cfg.IDMonitor)
-	if cfg.UserSchermo != "" {
-		params.Add("userschermo", cfg.UserSchermo)
-	}
-
-	fullURL := baseURL + "?" + params.Encode()
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := http.Get(fullURL)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	return nil
-}
-
-// GetDownloadSchedule ritorna orari download
-func (s *SchermoXml) GetDownloadSchedule() []string {
-	orari := []string{
-		s.Schermo.OraDownload01,
-		s.Schermo.OraDownload02,
-		s.Schermo.OraDownload03,
-		s.Schermo.OraDownload04,
-		s.Schermo.OraDownload05,
-		s.Schermo.OraDownload06,
-		s.Schermo.OraDownload07,
-		s.Schermo.OraDownload08,
-	}
-
-	var result []string
-	for _, ora := range orari {
-		if ora != "" && ora != "00:00:00" {
-			result = append(result, ora)
-		}
-	}
-	return result
-}
-
-// GetMediaFiles ritorna lista file media da scaricare
-func (s *SchermoXml) GetMediaFiles() []string {
-	var files []string
-	seen := make(map[string]bool)
-
-	for _, mf := range s.MediaFinestre {
-		if mf.Media.Video != "" && !seen[mf.Media.Video] {
-			files = append(files, mf.Media.Video)
-			seen[mf.Media.Video] = true
-		}
-		if mf.Media.Immagine != "" && !seen[mf.Media.Immagine] {
-			files = append(files, mf.Media.Immagine)
-			seen[mf.Media.Immagine] = true
-		}
-		if mf.Media.Audio != "" && !seen[mf.Media.Audio] {
-			files = append(files, mf.Media.Audio)
-			seen[mf.Media.Audio] = true
-		}
-	}
-
-	// Aggiungi immagine fallback
-	for _, finestra := range s.Schermo.Finestre {
-		if finestra.ImgNoInternet != "" && !seen[finestra.ImgNoInternet] {
-			files = append(files, finestra.ImgNoInternet)
-			seen[finestra.ImgNoInternet] = true
-		}
-	}
-
-	return files
-}
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"net/url"
+	"spotlive-server/internal/apperr"
+	"spotlive-server/internal/config"
+	"spotlive-server/internal/httpclient"
+	"sync"
+	"time"
+)
+
+// SchermoXml root structure
+type SchermoXml struct {
+	XMLName          xml.Name          `xml:"it.zerounorabbit.spotlivescreen.SchermoXml" json:"-"`
+	Schermo          Schermo           `xml:"schermo" json:"schermo"`
+	MediaFinestre    []MediaFinestra   `xml:"mediaFinestre>it.zerounorabbit.spotlivescreen.MediaFinestra" json:"mediaFinestre"`
+	Programmazioni   []Programmazione  `xml:"programmazioni>it.zerounorabbit.spotlivescreen.Programmazione" json:"programmazioni"`
+	Media            []Media           `xml:"media>it.zerounorabbit.spotlivescreen.Media" json:"media"`
+	FTP              *FTPInfo          `xml:"ftp" json:"-"` // opzionale, credenziali media
+
+	// Warnings campi non interpretabili (lasciati a zero) durante il parse
+	Warnings         []Warning         `xml:"-" json:"warnings,omitempty"`
+}
+
+// FTPInfo credenziali FTP fornite dal servlet dopo il login
+type FTPInfo struct {
+	Host       string `xml:"host"`
+	Port       Int    `xml:"port"`
+	Username   string `xml:"username"`
+	Password   string `xml:"password"`
+	Directory  string `xml:"directory"`
+}
+
+// Schermo configurazione display
+type Schermo struct {
+	ID                      Int                `xml:"id" json:"id"`
+	Nome                    string             `xml:"nome" json:"nome"`
+	Indirizzo               string             `xml:"indirizzo" json:"indirizzo"`
+	Larghezza               Int                `xml:"larghezza" json:"larghezza"`
+	Altezza                 Int                `xml:"altezza" json:"altezza"`
+	Attivo                  Bool               `xml:"attivo" json:"attivo"`
+	Finestre                []Finestra         `xml:"finestre>it.zerounorabbit.spotlivescreen.Finestra" json:"finestre"`
+	Orari                   []Orario           `xml:"orari>it.zerounorabbit.spotlivescreen.Orario" json:"orari"`
+	CategoriaMerceologica   Categoria          `xml:"categoriaMerceologica" json:"categoriaMerceologica"`
+	Lun                     Bool               `xml:"lun" json:"lun"`
+	Mar                     Bool               `xml:"mar" json:"mar"`
+	Mer                     Bool               `xml:"mer" json:"mer"`
+	Gio                     Bool               `xml:"gio" json:"gio"`
+	Ven                     Bool               `xml:"ven" json:"ven"`
+	Sab                     Bool               `xml:"sab" json:"sab"`
+	Dom                     Bool               `xml:"dom" json:"dom"`
+	Elenco                  Bool               `xml:"elenco" json:"elenco"`
+	OraDownload01           Ora                `xml:"oraDownload01" json:"oraDownload01"`
+	OraDownload02           Ora                `xml:"oraDownload02" json:"oraDownload02"`
+	OraDownload03           Ora                `xml:"oraDownload03" json:"oraDownload03"`
+	OraDownload04           Ora                `xml:"oraDownload04" json:"oraDownload04"`
+	OraDownload05           Ora                `xml:"oraDownload05" json:"oraDownload05"`
+	OraDownload06           Ora                `xml:"oraDownload06" json:"oraDownload06"`
+	OraDownload07           Ora                `xml:"oraDownload07" json:"oraDownload07"`
+	OraDownload08           Ora                `xml:"oraDownload08" json:"oraDownload08"`
+	OraRestart              Ora                `xml:"oraRestart" json:"oraRestart"`
+}
+
+// Finestra zona dello schermo
+type Finestra struct {
+	ID              Int    `xml:"id" json:"id"`
+	Nome            string `xml:"nome" json:"nome"`
+	Altezza         Int    `xml:"altezza" json:"altezza"`
+	Larghezza       Int    `xml:"larghezza" json:"larghezza"`
+	Alto            Int    `xml:"alto" json:"alto"`
+	Destra          Int    `xml:"destra" json:"destra"`
+	Attiva          Bool   `xml:"attiva" json:"attiva"`
+	Spot            Bool   `xml:"spot" json:"spot"`
+	ImgNoInternet   string `xml:"imgNoInternet" json:"imgNoInternet"`
+	Audio           Bool   `xml:"audio" json:"audio"`
+}
+
+// Orario fascia oraria
+type Orario struct {
+	ID         Int    `xml:"id" json:"id"`
+	OraInizio  Ora    `xml:"oraInizio" json:"oraInizio"`
+	OraFine    Ora    `xml:"oraFine" json:"oraFine"`
+	Crediti    Int    `xml:"crediti" json:"crediti"`
+}
+
+// MediaFinestra associazione media-finestra
+type MediaFinestra struct {
+	ID        Int    `xml:"id" json:"id"`
+	Tipo      string `xml:"tipo" json:"tipo"`
+	Ordine    Int    `xml:"ordine" json:"ordine"`
+	Media     Media  `xml:"media" json:"media"`
+}
+
+// Media contenuto multimediale
+type Media struct {
+	ID             Int       `xml:"id" json:"id"`
+	Nome           string    `xml:"nome" json:"nome"`
+	Attivo         Bool      `xml:"attivo" json:"attivo"`
+	Tipo           string    `xml:"tipo" json:"tipo"`
+	Video          string    `xml:"video" json:"video"`
+	Immagine       string    `xml:"immagine" json:"immagine"`
+	Audio          string    `xml:"audio" json:"audio"`
+	Miniatura      string    `xml:"miniatura" json:"miniatura"`
+	Pubblico       Bool      `xml:"pubblico" json:"pubblico"`
+	Tempo          Int       `xml:"tempo" json:"tempo"`
+	NumeroNotizie  Int       `xml:"numeroNotizie" json:"numeroNotizie"`
+	Approvato      Bool      `xml:"approvato" json:"approvato"`
+	Crediti        Int       `xml:"crediti" json:"crediti"`
+	Categoria      Categoria `xml:"categoria" json:"categoria"`
+}
+
+// Categoria categoria merceologica
+type Categoria struct {
+	ID   Int    `xml:"id" json:"id"`
+	Nome string `xml:"nome" json:"nome"`
+}
+
+var (
+	lastMu       sync.RWMutex
+	lastSchedule *SchermoXml
+	subscribers  = make(map[int]chan *SchermoXml)
+	nextSubID    int
+)
+
+// Subscribe ritorna canale che riceve ogni programmazione scaricata (solo
+// l'ultima se il lettore è lento) e funzione per annullare la sottoscrizione
+func Subscribe() (<-chan *SchermoXml, func()) {
+	lastMu.Lock()
+	defer lastMu.Unlock()
+
+	id := nextSubID
+	nextSubID++
+	ch := make(chan *SchermoXml, 1)
+	subscribers[id] = ch
+
+	return ch, func() {
+		lastMu.Lock()
+		defer lastMu.Unlock()
+		if _, ok := subscribers[id]; ok {
+			delete(subscribers, id)
+			close(ch)
+		}
+	}
+}
+
+// publish salva programmazione e notifica i sottoscrittori
+func publish(schedule *SchermoXml) {
+	lastMu.Lock()
+	defer lastMu.Unlock()
+
+	lastSchedule = schedule
+	for _, ch := range subscribers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- schedule
+	}
+}
+
+// LastSchedule ritorna ultima programmazione scaricata con successo (nil se nessuna)
+func LastSchedule() *SchermoXml {
+	lastMu.RLock()
+	defer lastMu.RUnlock()
+	return lastSchedule
+}
+
+// ResetLastSchedule dimentica ultima programmazione (schermo/server cambiati)
+func ResetLastSchedule() {
+	lastMu.Lock()
+	lastSchedule = nil
+	lastMu.Unlock()
+}
+
+// FetchSchedule scarica programmazione dal server
+func FetchSchedule() (*SchermoXml, error) {
+	if err := config.CheckConfigured(); err != nil {
+		return nil, err
+	}
+
+	cfg := config.Get()
+
+	// Parametri del vecchio client; path, versione e autenticazione da config
+	params := url.Values{}
+	params.Add("idSchermo", cfg.IDMonitor)
+	if cfg.UserSchermo != "" {
+		params.Add("userschermo", cfg.UserSchermo)
+	}
+
+	req, err := newServletRequest(cfg, params)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := httpclient.Do(req)
+	if err != nil {
+		if apperr.From(err) != nil {
+			return nil, err
+		}
+		return nil, apperr.Wrap(err, CodeServerUnreachable, "HTTP request failed").
+			WithDetail("serverUrl", cfg.ServerURL).
+			AsRetryable()
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		forgetCapabilitiesOnAuthError(cfg, resp.StatusCode)
+		return nil, httpStatusError(resp.StatusCode)
+	}
+
+	// Body oltre il limite rifiutato senza leggerlo (se dichiarato)
+	limit := int64(cfg.MaxScheduleBytes)
+	if limit <= 0 {
+		limit = config.DefaultMaxScheduleBytes
+	}
+	if resp.ContentLength > limit {
+		return nil, tooLargeError(limit).WithDetail("contentLength", resp.ContentLength)
+	}
+
+	// Body su file temporaneo, poi parse in streaming
+	f, err := spoolBody(resp.Body, limit, cfg.CacheDir)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	schedule, err := ParseReader(f)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range schedule.Warnings {
+		log.Printf("Warning: schedule field %s=%q (line %d): %s", w.Field, w.Value, w.Line, w.Message)
+	}
+
+	publish(schedule)
+
+	adoptFTPCredentials(schedule.FTP)
+
+	return schedule, nil
+}
+
+// Parse decodifica una risposta XmlServlet. Solo XML malformato è un
+// errore: valori di campo non validi diventano Warnings.
+func Parse(data []byte) (*SchermoXml, error) {
+	return ParseReader(bytes.NewReader(data))
+}
+
+// ParseReader come Parse, leggendo il documento token per token da r. Un
+// primo passaggio cerca attributi reference: solo se presenti il documento
+// viene caricato in un albero per espanderli. Gli errori tipizzati del
+// lettore (es. SCHEDULE_TOO_LARGE) sono ritornati invariati.
+func ParseReader(r io.ReadSeeker) (*SchermoXml, error) {
+	hasRefs, err := containsReference(r)
+	if err != nil {
+		return nil, parseError(err)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, parseError(err)
+	}
+
+	var (
+		schedule    SchermoXml
+		refWarnings []Warning
+		d           *xml.Decoder
+		line        func() int
+	)
+	if hasRefs {
+		doc, w, err := resolveReferences(r)
+		if err != nil {
+			return nil, parseError(err)
+		}
+		tr := newTreeReader(doc)
+		refWarnings = w
+		d = xml.NewTokenDecoder(tr)
+		line = func() int { return tr.line }
+	} else {
+		d = xml.NewDecoder(r)
+		d.CharsetReader = charsetReader
+		line = func() int {
+			l, _ := d.InputPos()
+			return l
+		}
+	}
+
+	warnings, err := decodeLenient(d, line, &schedule)
+	if err != nil {
+		return nil, parseError(err)
+	}
+	schedule.Warnings = append(refWarnings, warnings...)
+	return &schedule, nil
+}
+
+// parseError classifica un errore di lettura o decodifica
+func parseError(err error) error {
+	if apperr.From(err) != nil {
+		return err
+	}
+	// Body interrotto (connessione chiusa, timeout): ritentabile
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return apperr.Wrap(err, CodeScheduleRead, "read body failed").AsRetryable()
+	}
+	return apperr.Wrap(err, CodeScheduleParse, "XML parse failed")
+}
+
+// adoptFTPCredentials salva le credenziali FTP ricevute dal servlet se
+// il dispositivo non ne ha (quelle da provisioning non vengono sovrascritte)
+func adoptFTPCredentials(info *FTPInfo) {
+	if info == nil || info.Host == "" || info.Username == "" || info.Password == "" {
+		return
+	}
+
+	cfg := config.Get()
+	if cfg.FTPServer != "" && cfg.FTPUsername != "" && cfg.FTPPassword != "" {
+		return
+	}
+
+	cfg.FTPServer = info.Host
+	cfg.FTPUsername = info.Username
+	cfg.FTPPassword = info.Password
+	if info.Port > 0 {
+		cfg.FTPPort = int(info.Port)
+	}
+	if info.Directory != "" {
+		cfg.FTPDirectory = info.Directory
+	}
+
+	if errs := cfg.Validate(); errs != nil {
+		log.Printf("Warning: ignoring FTP credentials from server: %v", errs)
+		return
+	}
+	if err := config.Save(cfg); err != nil {
+		log.Printf("Warning: saving FTP credentials from server failed: %v", err)
+		return
+	}
+	log.Printf("FTP credentials received from server (%s)", info.Host)
+}
+
+// SendUpdate invia heartbeat al server
+func SendUpdate() error {
+	if err := config.CheckConfigured(); err != nil {
+		return err
+	}
+
+	cfg := config.Get()
+
+	params := url.Values{}
+	params.Add("update", "1")
+	params.Add("idSchermo", cfg.IDMonitor)
+	if cfg.UserSchermo != "" {
+		params.Add("userschermo", cfg.UserSchermo)
+	}
+
+	req, err := newServletRequest(cfg, params)
+	if err != nil {
+		return err
+	}
+
+	resp, err := httpclient.Do(req)
+	if err != nil {
+		if apperr.From(err) != nil {
+			return err
+		}
+		return apperr.Wrap(err, CodeServerUnreachable, "HTTP request failed").
+			WithDetail("serverUrl", cfg.ServerURL).
+			AsRetryable()
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		forgetCapabilitiesOnAuthError(cfg, resp.StatusCode)
+		return httpStatusError(resp.StatusCode)
+	}
+
+	return nil
+}
+
+// GetDownloadSchedule ritorna orari download
+func (s *SchermoXml) GetDownloadSchedule() []string {
+	orari := []Ora{
+		s.Schermo.OraDownload01,
+		s.Schermo.OraDownload02,
+		s.Schermo.OraDownload03,
+		s.Schermo.OraDownload04,
+		s.Schermo.OraDownload05,
+		s.Schermo.OraDownload06,
+		s.Schermo.OraDownload07,
+		s.Schermo.OraDownload08,
+	}
+
+	var result []string
+	for _, ora := range orari {
+		if ora != "" && ora != "00:00:00" {
+			result = append(result, string(ora))
+		}
+	}
+	return result
+}
+
+// GetMediaFiles ritorna lista file media da scaricare
+func (s *SchermoXml) GetMediaFiles() []string {
+	return s.MediaFilesAt(time.Now())
+}
+
+// MediaFilesAt ritorna i file media necessari a t: esclusi quelli delle
+// sole campagne già terminate (le campagne future vanno scaricate prima)
+func (s *SchermoXml) MediaFilesAt(t time.Time) []string {
+	var files []string
+	seen := make(map[string]bool)
+	campagne := s.campagne()
+
+	for _, mf := range s.MediaFinestre {
+		if mediaScaduto(campagne[mf.Media.ID], t) {
+			continue
+		}
+		if mf.Media.Video != "" && !seen[mf.Media.Video] {
+			files = append(files, mf.Media.Video)
+			seen[mf.Media.Video] = true
+		}
+		if mf.Media.Immagine != "" && !seen[mf.Media.Immagine] {
+			files = append(files, mf.Media.Immagine)
+			seen[mf.Media.Immagine] = true
+		}
+		if mf.Media.Audio != "" && !seen[mf.Media.Audio] {
+			files = append(files, mf.Media.Audio)
+			seen[mf.Media.Audio] = true
+		}
+	}
+
+	// Aggiungi immagine fallback
+	for _, finestra := range s.Schermo.Finestre {
+		if finestra.ImgNoInternet != "" && !seen[finestra.ImgNoInternet] {
+			files = append(files, finestra.ImgNoInternet)
+			seen[finestra.ImgNoInternet] = true
+		}
+	}
+
+	return files
+}
+
+// MediaSources identità CMS dei file media (path → "media:<id>/<ruolo>"):
+// stabile anche se il file viene rinominato sul server
+func (s *SchermoXml) MediaSources() map[string]string {
+	sources := make(map[string]string)
+	for _, mf := range s.MediaFinestre {
+		if mf.Media.ID <= 0 {
+			continue
+		}
+		for role, file := range map[string]string{
+			"video":    mf.Media.Video,
+			"immagine": mf.Media.Immagine,
+			"audio":    mf.Media.Audio,
+		} {
+			if file != "" {
+				sources[file] = fmt.Sprintf("media:%d/%s", mf.Media.ID, role)
+			}
+		}
+	}
+	return sources
+}
@@ -0,0 +1,270 @@
+package xml
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Programmazione campagna pubblicitaria: media trasmessi solo tra
+// DataInizio e DataFine sulle finestre indicate. Struttura ricavata dalle
+// risposte del servlet (serializzazione XStream, vedi testdata/).
+type Programmazione struct {
+	ID         Int        `xml:"id" json:"id"`
+	Nome       string     `xml:"nome" json:"nome"`
+	DataInizio DataOra    `xml:"dataInizio" json:"dataInizio"`
+	DataFine   DataOra    `xml:"dataFine" json:"dataFine"`
+	Attiva     *Bool      `xml:"attiva" json:"attiva"` // assente = attiva
+	Finestre   []Finestra `xml:"finestre>it.zerounorabbit.spotlivescreen.Finestra" json:"finestre"`
+	Media      []Media    `xml:"media>it.zerounorabbit.spotlivescreen.Media" json:"media"`
+}
+
+// DataOra data del servlet. Accetta i formati XStream di java.util.Date
+// ("2024-06-01 00:00:00.0 CEST") e java.sql.Timestamp, ISO 8601 e sola
+// data. Senza fuso esplicito si usa l'ora locale del dispositivo.
+type DataOra struct {
+	time.Time
+	// SoloData true se il valore non aveva orario (DataFine vale tutto il giorno)
+	SoloData bool
+}
+
+// layout provati in ordine (la frazione di secondo è sempre accettata)
+var dataOraLayouts = []struct {
+	layout   string
+	soloData bool
+}{
+	{time.RFC3339, false},
+	{"2006-01-02 15:04:05", false},
+	{"2006-01-02T15:04:05", false},
+	{"2006-01-02 15:04", false},
+	{"2006-01-02", true},
+	{"02/01/2006 15:04:05", false},
+	{"02/01/2006", true},
+}
+
+// ParseDataOra interpreta una data del servlet ("" = nessun limite)
+func ParseDataOra(value string) (DataOra, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return DataOra{}, nil
+	}
+
+	// Abbreviazione fuso XStream (CET, CEST, UTC): Go non la risolve,
+	// UTC/GMT esplicite, le altre sono l'ora locale
+	loc := time.Local
+	if i := strings.LastIndexByte(value, ' '); i > 0 && isZoneAbbrev(value[i+1:]) {
+		switch value[i+1:] {
+		case "UTC", "GMT", "Z":
+			loc = time.UTC
+		}
+		value = value[:i]
+	}
+
+	for _, l := range dataOraLayouts {
+		if t, err := time.ParseInLocation(l.layout, value, loc); err == nil {
+			return DataOra{Time: t, SoloData: l.soloData}, nil
+		}
+	}
+	return DataOra{}, fmt.Errorf("invalid date %q", value)
+}
+
+func isZoneAbbrev(s string) bool {
+	if len(s) < 1 || len(s) > 5 {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsUpper(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// UnmarshalXML decodifica data, warning (e nessun limite) se non valida
+func (d *DataOra) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	var s string
+	if err := dec.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+	parsed, err := ParseDataOra(s)
+	if err != nil {
+		warn(dec, start, s, "invalid date")
+	}
+	*d = parsed
+	return nil
+}
+
+// MarshalJSON serializza in RFC 3339 (null se assente)
+func (d DataOra) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(d.Time.Format(time.RFC3339))
+}
+
+// MarshalJSON aggiunge "fine": istante (escluso) di fine validità già
+// calcolato, così i client non devono interpretare le date solo-giorno
+func (p Programmazione) MarshalJSON() ([]byte, error) {
+	type plain Programmazione
+	out := struct {
+		plain
+		Fine *string `json:"fine"`
+	}{plain: plain(p)}
+	if end := p.Fine(); !end.IsZero() {
+		// Format e non time.Time.MarshalJSON: anni oltre il 9999 non falliscono
+		fine := end.Format(time.RFC3339)
+		out.Fine = &fine
+	}
+	return json.Marshal(out)
+}
+
+// Fine ritorna istante (escluso) di fine validità; zero = senza scadenza
+func (p *Programmazione) Fine() time.Time {
+	if p.DataFine.IsZero() {
+		return time.Time{}
+	}
+	if p.DataFine.SoloData {
+		return p.DataFine.AddDate(0, 0, 1)
+	}
+	// Fine inclusiva al secondo (es. 23:59:59)
+	return p.DataFine.Add(time.Second)
+}
+
+// ActiveAt verifica se la campagna è in onda all'istante t
+func (p *Programmazione) ActiveAt(t time.Time) bool {
+	if p.Attiva != nil && !*p.Attiva {
+		return false
+	}
+	if !p.DataInizio.IsZero() && t.Before(p.DataInizio.Time) {
+		return false
+	}
+	if end := p.Fine(); !end.IsZero() && !t.Before(end) {
+		return false
+	}
+	return true
+}
+
+// ExpiredAt verifica se la campagna è terminata (o disattivata) a t.
+// Una campagna futura non è scaduta: i suoi media vanno già scaricati.
+func (p *Programmazione) ExpiredAt(t time.Time) bool {
+	if p.Attiva != nil && !*p.Attiva {
+		return true
+	}
+	end := p.Fine()
+	return !end.IsZero() && !t.Before(end)
+}
+
+// TargetsFinestra verifica se la campagna è destinata alla finestra
+// (nessuna finestra indicata = tutte le finestre dello schermo)
+func (p *Programmazione) TargetsFinestra(id Int) bool {
+	if len(p.Finestre) == 0 {
+		return true
+	}
+	for _, f := range p.Finestre {
+		if f.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// finestreAttive ID delle finestre attive dello schermo (nil se lo schermo
+// non ne elenca: finestre delle campagne non verificabili)
+func (s *SchermoXml) finestreAttive() []Int {
+	if len(s.Schermo.Finestre) == 0 {
+		return nil
+	}
+	ids := []Int{}
+	for _, f := range s.Schermo.Finestre {
+		if f.Attiva {
+			ids = append(ids, f.ID)
+		}
+	}
+	return ids
+}
+
+// campagne ritorna le campagne che referenziano ogni media (per ID)
+func (s *SchermoXml) campagne() map[Int][]*Programmazione {
+	byMedia := make(map[Int][]*Programmazione)
+	for i := range s.Programmazioni {
+		p := &s.Programmazioni[i]
+		for _, m := range p.Media {
+			byMedia[m.ID] = append(byMedia[m.ID], p)
+		}
+	}
+	return byMedia
+}
+
+// mediaInOnda verifica se il media può andare in onda a t su una delle
+// finestre indicate (nil = qualsiasi): i media senza campagna sono sempre
+// in onda, gli altri se almeno una campagna è attiva su quelle finestre
+func mediaInOnda(campagne []*Programmazione, finestre []Int, t time.Time) bool {
+	if len(campagne) == 0 {
+		return true
+	}
+	for _, p := range campagne {
+		if !p.ActiveAt(t) {
+			continue
+		}
+		if finestre == nil {
+			return true
+		}
+		for _, id := range finestre {
+			if p.TargetsFinestra(id) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mediaScaduto verifica se tutte le campagne del media sono terminate
+func mediaScaduto(campagne []*Programmazione, t time.Time) bool {
+	if len(campagne) == 0 {
+		return false
+	}
+	for _, p := range campagne {
+		if !p.ExpiredAt(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// Playlist ritorna i media da trasmettere a t: attivi, approvati, con
+// campagna in onda su almeno una finestra attiva dello schermo, ordinati
+// per Ordine
+func (s *SchermoXml) Playlist(t time.Time) []MediaFinestra {
+	return s.playlist(s.finestreAttive(), t)
+}
+
+// PlaylistFinestra come Playlist ma per la sola finestra indicata: i media
+// di campagne destinate ad altre finestre sono esclusi
+func (s *SchermoXml) PlaylistFinestra(finestra Int, t time.Time) []MediaFinestra {
+	return s.playlist([]Int{finestra}, t)
+}
+
+// playlist media in onda a t sulle finestre indicate (nil = qualsiasi)
+func (s *SchermoXml) playlist(finestre []Int, t time.Time) []MediaFinestra {
+	campagne := s.campagne()
+
+	playlist := []MediaFinestra{}
+	for _, mf := range s.MediaFinestre {
+		if !mf.Media.Attivo || !mf.Media.Approvato {
+			continue
+		}
+		if !mediaInOnda(campagne[mf.Media.ID], finestre, t) {
+			continue
+		}
+		playlist = append(playlist, mf)
+	}
+
+	sort.SliceStable(playlist, func(i, j int) bool {
+		return playlist[i].Ordine < playlist[j].Ordine
+	})
+	return playlist
+}
@@ -0,0 +1,198 @@
+package xml
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// loadFixture decodifica una risposta del servlet da testdata/
+func loadFixture(t *testing.T, name string) *SchermoXml {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Parse(data)
+	if err != nil {
+		t.Fatalf("parse %s: %v", name, err)
+	}
+	return s
+}
+
+func localTime(t *testing.T, value string) time.Time {
+	t.Helper()
+
+	tm, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.Local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tm
+}
+
+func TestProgrammazioniDecoded(t *testing.T) {
+	s := loadFixture(t, "programmazioni.xml")
+
+	if len(s.Programmazioni) != 2 {
+		t.Fatalf("programmazioni = %d; want 2", len(s.Programmazioni))
+	}
+
+	estate := s.Programmazioni[0]
+	if estate.ID != 100 || estate.Nome != "Campagna estate" {
+		t.Errorf("campaign = %d %q", estate.ID, estate.Nome)
+	}
+	if !estate.DataInizio.Equal(localTime(t, "2024-06-01 00:00:00")) {
+		t.Errorf("dataInizio = %v", estate.DataInizio.Time)
+	}
+	if !estate.DataFine.SoloData {
+		t.Error("dataFine without time not marked as date only")
+	}
+	if len(estate.Finestre) != 1 || estate.Finestre[0].ID != 3 {
+		t.Errorf("finestre = %+v", estate.Finestre)
+	}
+	if len(estate.Media) != 1 || estate.Media[0].ID != 10 {
+		t.Errorf("media = %+v", estate.Media)
+	}
+	if estate.Attiva == nil || !*estate.Attiva {
+		t.Error("attiva not decoded")
+	}
+
+	if primavera := s.Programmazioni[1]; primavera.Attiva != nil {
+		t.Error("missing attiva should stay nil")
+	}
+}
+
+func TestProgrammazioniJSON(t *testing.T) {
+	s := loadFixture(t, "programmazioni.xml")
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Programmazioni []map[string]interface{} `json:"programmazioni"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Programmazioni) != 2 {
+		t.Fatalf("programmazioni JSON = %s", data)
+	}
+	if _, ok := out.Programmazioni[0]["dataFine"].(string); !ok {
+		t.Errorf("dataFine = %v; want RFC 3339 string", out.Programmazioni[0]["dataFine"])
+	}
+
+	// Sola data: fine esclusiva al giorno successivo
+	fine, _ := time.Parse(time.RFC3339, out.Programmazioni[0]["fine"].(string))
+	if !fine.Equal(localTime(t, "2024-09-01 00:00:00")) {
+		t.Errorf("fine = %v; want 2024-09-01 00:00 local", out.Programmazioni[0]["fine"])
+	}
+}
+
+func TestPlaylistExcludesExpiredCampaigns(t *testing.T) {
+	s := loadFixture(t, "programmazioni.xml")
+
+	ids := func(list []MediaFinestra) []int {
+		out := []int{}
+		for _, mf := range list {
+			out = append(out, int(mf.Media.ID))
+		}
+		return out
+	}
+
+	tests := []struct {
+		at   string
+		want []int
+	}{
+		{"2024-04-15 12:00:00", []int{11, 12}}, // primavera in onda (ordine 1)
+		{"2024-05-31 23:59:59", []int{11, 12}}, // fine inclusiva
+		{"2024-06-01 00:00:00", []int{10, 12}}, // primavera scaduta, estate iniziata
+		{"2024-08-31 22:00:00", []int{10, 12}}, // sola data: vale tutto il giorno
+		{"2024-09-01 00:00:00", []int{12}},     // entrambe scadute
+		{"2024-01-01 00:00:00", []int{12}},     // nessuna ancora iniziata
+	}
+	for _, tt := range tests {
+		got := ids(s.Playlist(localTime(t, tt.at)))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Playlist(%s) = %v; want %v", tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestPlaylistFiltersByTargetedWindow(t *testing.T) {
+	s := loadFixture(t, "campaign_windows.xml")
+	at := localTime(t, "2024-07-01 12:00:00")
+
+	ids := func(list []MediaFinestra) []int {
+		out := []int{}
+		for _, mf := range list {
+			out = append(out, int(mf.Media.ID))
+		}
+		return out
+	}
+
+	tests := []struct {
+		name string
+		got  []MediaFinestra
+		want []int
+	}{
+		// 602 solo sulla finestra spenta, 603 su una finestra di altro schermo
+		{"screen", s.Playlist(at), []int{601, 604, 605}},
+		{"window 1", s.PlaylistFinestra(1, at), []int{601, 604, 605}},
+		{"window 2", s.PlaylistFinestra(2, at), []int{604, 605}},
+		{"window 3", s.PlaylistFinestra(3, at), []int{602, 604, 605}},
+	}
+	for _, tt := range tests {
+		if got := ids(tt.got); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s playlist = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMediaFilesSkipExpiredOnly(t *testing.T) {
+	s := loadFixture(t, "programmazioni.xml")
+
+	// Prima di entrambe: anche i media futuri vanno scaricati
+	got := s.MediaFilesAt(localTime(t, "2024-01-01 00:00:00"))
+	want := []string{"upload/estate.mp4", "upload/primavera.jpg", "upload/logo.png", "upload/nointernet.jpg"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MediaFilesAt(before) = %v; want %v", got, want)
+	}
+
+	got = s.MediaFilesAt(localTime(t, "2024-07-01 00:00:00"))
+	want = []string{"upload/estate.mp4", "upload/logo.png", "upload/nointernet.jpg"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MediaFilesAt(summer) = %v; want %v", got, want)
+	}
+}
+
+func TestParseDataOra(t *testing.T) {
+	for _, v := range []string{
+		"2024-06-01 10:30:00.0 CEST",
+		"2024-06-01 10:30:00.0",
+		"2024-06-01 10:30:00",
+		"2024-06-01T10:30:00",
+	} {
+		d, err := ParseDataOra(v)
+		if err != nil {
+			t.Errorf("ParseDataOra(%q): %v", v, err)
+			continue
+		}
+		if !d.Equal(localTime(t, "2024-06-01 10:30:00")) || d.SoloData {
+			t.Errorf("ParseDataOra(%q) = %v", v, d.Time)
+		}
+	}
+
+	if d, err := ParseDataOra("2024-06-01 10:30:00 UTC"); err != nil || d.Location() != time.UTC {
+		t.Errorf("UTC suffix: %v, %v", d.Time, err)
+	}
+	if d, err := ParseDataOra(""); err != nil || !d.IsZero() {
+		t.Errorf("empty date: %v, %v", d.Time, err)
+	}
+	if _, err := ParseDataOra("domani"); err == nil {
+		t.Error("invalid date accepted")
+	}
+}
@@ -0,0 +1,305 @@
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// XStream deduplica oggetti ripetuti: la seconda occorrenza di un Media o
+// di una Categoria arriva vuota con un attributo reference, in due stili:
+//
+//	path: <media reference="../../it.zerounorabbit.spotlivescreen.MediaFinestra/media"/>
+//	      (relativo all'elemento stesso, o assoluto se inizia con "/")
+//	id:   <media id="5">...</media> ... <media reference="5"/>
+//
+// resolveReferences sostituisce ogni riferimento con una copia dell'elemento
+// referenziato prima della decodifica.
+
+// maxExpandedNodes limite elementi del documento, anche dopo l'espansione
+// dei riferimenti (riferimenti annidati possono moltiplicarne la dimensione)
+const maxExpandedNodes = 200000
+
+// maxExpandedBytes limite del testo copiato dall'espansione dei riferimenti
+// (il testo originale è già limitato da maxScheduleBytes)
+const maxExpandedBytes = 32 << 20
+
+// node elemento XML con contenuto (xml.CharData, xml.Comment o *node)
+type node struct {
+	start  xml.StartElement
+	items  []interface{}
+	parent *node
+	line   int
+}
+
+// attr ritorna valore attributo (senza namespace)
+func (n *node) attr(name string) (string, bool) {
+	for _, a := range n.start.Attr {
+		if a.Name.Local == name && a.Name.Space == "" {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// elements ritorna gli elementi figli
+func (n *node) elements() []*node {
+	var out []*node
+	for _, it := range n.items {
+		if c, ok := it.(*node); ok {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// isAncestorOf verifica se n contiene other
+func (n *node) isAncestorOf(other *node) bool {
+	for p := other.parent; p != nil; p = p.parent {
+		if p == n {
+			return true
+		}
+	}
+	return false
+}
+
+// referenceResolver stato della risoluzione
+type referenceResolver struct {
+	doc      *node // radice virtuale (figli: prolog e elemento radice)
+	ids      map[string]*node
+	nodes    int
+	bytes    int
+	hasRefs  bool
+	warnings []Warning
+}
+
+// resolveReferences legge il documento token per token e ritorna l'albero
+// (radice virtuale) con i riferimenti XStream espansi
+func resolveReferences(in io.Reader) (*node, []Warning, error) {
+	r := &referenceResolver{ids: make(map[string]*node)}
+	if err := r.build(in); err != nil {
+		return nil, nil, err
+	}
+	if !r.hasRefs {
+		return r.doc, nil, nil
+	}
+	if err := r.resolve(r.doc); err != nil {
+		return nil, r.warnings, err
+	}
+	return r.doc, r.warnings, nil
+}
+
+// build costruisce l'albero (contenuto convertito in UTF-8)
+func (r *referenceResolver) build(in io.Reader) error {
+	d := xml.NewDecoder(in)
+	d.CharsetReader = charsetReader
+
+	r.doc = &node{}
+	cur := r.doc
+	for {
+		line, _ := d.InputPos()
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			r.nodes++
+			if r.nodes > maxExpandedNodes {
+				return fmt.Errorf("schedule exceeds %d elements", maxExpandedNodes)
+			}
+			n := &node{start: t.Copy(), parent: cur, line: line}
+			cur.items = append(cur.items, n)
+			cur = n
+			if id, ok := n.attr("id"); ok {
+				r.ids[id] = n
+			}
+			if _, ok := n.attr("reference"); ok {
+				r.hasRefs = true
+			}
+		case xml.EndElement:
+			cur = cur.parent
+		case xml.CharData:
+			cur.items = append(cur.items, t.Copy())
+		case xml.Comment:
+			cur.items = append(cur.items, t.Copy())
+		case xml.ProcInst:
+			// Contenuto già in UTF-8: la dichiarazione non serve più
+			if t.Target != "xml" {
+				cur.items = append(cur.items, t.Copy())
+			}
+		}
+	}
+	return nil
+}
+
+// resolve espande i riferimenti in ordine di documento. Il target precede
+// sempre il riferimento, quindi è già risolto quando viene copiato.
+func (r *referenceResolver) resolve(n *node) error {
+	for _, it := range n.items {
+		c, ok := it.(*node)
+		if !ok {
+			continue
+		}
+
+		ref, isRef := c.attr("reference")
+		if !isRef {
+			if err := r.resolve(c); err != nil {
+				return err
+			}
+			continue
+		}
+
+		target, err := r.lookup(c, ref)
+		if err == nil && (target == c || target.isAncestorOf(c)) {
+			err = errors.New("circular reference")
+		}
+		if err != nil {
+			r.warnings = append(r.warnings, Warning{
+				Field:   c.start.Name.Local,
+				Value:   ref,
+				Line:    c.line,
+				Message: "unresolved reference: " + err.Error(),
+			})
+			continue
+		}
+
+		if err := r.expand(c, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expand copia attributi e contenuto del target nell'elemento c
+// (il nome resta quello di c: determina il campo di destinazione)
+func (r *referenceResolver) expand(c, target *node) error {
+	attrs := make([]xml.Attr, 0, len(target.start.Attr))
+	for _, a := range target.start.Attr {
+		if a.Name.Space == "" && (a.Name.Local == "id" || a.Name.Local == "reference") {
+			continue
+		}
+		attrs = append(attrs, a)
+	}
+	c.start.Attr = attrs
+
+	items, err := r.cloneItems(target.items, c)
+	if err != nil {
+		return err
+	}
+	c.items = items
+	return nil
+}
+
+// cloneItems copia profonda del contenuto. Gli a-capo della copia vengono
+// rimossi per non spostare i numeri di riga dei warning successivi.
+func (r *referenceResolver) cloneItems(items []interface{}, parent *node) ([]interface{}, error) {
+	out := make([]interface{}, 0, len(items))
+	for _, it := range items {
+		switch t := it.(type) {
+		case *node:
+			r.nodes++
+			if r.nodes > maxExpandedNodes {
+				return nil, fmt.Errorf("schedule exceeds %d elements after reference expansion", maxExpandedNodes)
+			}
+			n := &node{start: t.start.Copy(), parent: parent, line: parent.line}
+			children, err := r.cloneItems(t.items, n)
+			if err != nil {
+				return nil, err
+			}
+			n.items = children
+			out = append(out, n)
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) == 0 {
+				continue
+			}
+			r.bytes += len(t)
+			if r.bytes > maxExpandedBytes {
+				return nil, fmt.Errorf("schedule exceeds %d bytes of text after reference expansion", maxExpandedBytes)
+			}
+			out = append(out, t.Copy())
+		case xml.Comment:
+			// Commenti non copiati
+		default:
+			out = append(out, it)
+		}
+	}
+	return out, nil
+}
+
+// lookup trova l'elemento referenziato (id o path XStream)
+func (r *referenceResolver) lookup(from *node, ref string) (*node, error) {
+	if target, ok := r.ids[ref]; ok {
+		return target, nil
+	}
+
+	cur := from
+	path := ref
+	if strings.HasPrefix(ref, "/") {
+		cur = r.doc
+		path = strings.TrimPrefix(ref, "/")
+	}
+
+	for _, step := range strings.Split(path, "/") {
+		switch step {
+		case "", ".":
+			continue
+		case "..":
+			if cur.parent == nil || cur.parent == r.doc {
+				return nil, errors.New("path above document root")
+			}
+			cur = cur.parent
+			continue
+		}
+
+		name, index, err := parseStep(step)
+		if err != nil {
+			return nil, err
+		}
+		var next *node
+		seen := 0
+		for _, c := range cur.elements() {
+			if c.start.Name.Local != name {
+				continue
+			}
+			seen++
+			if seen == index {
+				next = c
+				break
+			}
+		}
+		if next == nil {
+			return nil, fmt.Errorf("no element %s", step)
+		}
+		cur = next
+	}
+
+	if cur == r.doc {
+		return nil, errors.New("empty path")
+	}
+	return cur, nil
+}
+
+// parseStep interpreta "nome" o "nome[n]" (n da 1)
+func parseStep(step string) (string, int, error) {
+	open := strings.IndexByte(step, '[')
+	if open < 0 {
+		return step, 1, nil
+	}
+	if !strings.HasSuffix(step, "]") || open == 0 {
+		return "", 0, fmt.Errorf("invalid path step %q", step)
+	}
+	n, err := strconv.Atoi(step[open+1 : len(step)-1])
+	if err != nil || n < 1 {
+		return "", 0, fmt.Errorf("invalid path step %q", step)
+	}
+	return step[:open], n, nil
+}
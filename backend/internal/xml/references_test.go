@@ -0,0 +1,39 @@
+package xml
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReferenceToAncestorIsWarning(t *testing.T) {
+	data := `<it.zerounorabbit.spotlivescreen.SchermoXml>
+  <mediaFinestre>
+    <it.zerounorabbit.spotlivescreen.MediaFinestra>
+      <media reference="../.."/>
+    </it.zerounorabbit.spotlivescreen.MediaFinestra>
+  </mediaFinestre>
+</it.zerounorabbit.spotlivescreen.SchermoXml>`
+
+	s, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Warnings) != 1 || !strings.Contains(s.Warnings[0].Message, "circular") {
+		t.Errorf("warnings = %+v; want circular reference", s.Warnings)
+	}
+}
+
+func TestReferenceExpansionLimit(t *testing.T) {
+	// Ogni livello referenzia due volte il precedente: crescita esponenziale
+	var b strings.Builder
+	b.WriteString(`<r><l0 id="l0"><a/><a/></l0>`)
+	for i := 1; i < 40; i++ {
+		fmt.Fprintf(&b, `<l%d id="l%d"><x reference="l%d"/><x reference="l%d"/></l%d>`, i, i, i-1, i-1, i)
+	}
+	b.WriteString("</r>")
+
+	if _, err := Parse([]byte(b.String())); err == nil {
+		t.Fatal("exponential reference expansion accepted")
+	}
+}
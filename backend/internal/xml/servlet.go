@@ -0,0 +1,302 @@
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"spotlive-server/internal/apperr"
+	"spotlive-server/internal/config"
+	"spotlive-server/internal/httpclient"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Negoziazione: GET <servlet>?capabilities=1 prima della prima chiamata.
+// I servlet recenti rispondono con
+//
+//	<capabilities>
+//	  <version>600</version><version>700</version>
+//	  <auth>basic</auth><auth>query</auth>
+//	</capabilities>
+//
+// Un 401 con WWW-Authenticate indica la modalità richiesta. Qualsiasi altra
+// risposta (servlet storico) lascia i valori configurati, autenticazione none.
+
+// Capabilities capacità rilevate del servlet
+type Capabilities struct {
+	Versions   []int     `json:"versions"`
+	AuthModes  []string  `json:"authModes"`
+	Negotiated bool      `json:"negotiated"` // false = servlet storico o non raggiungibile
+	CheckedAt  time.Time `json:"checkedAt"`
+}
+
+// ServletInfo parametri effettivi delle chiamate al servlet
+type ServletInfo struct {
+	URL             string       `json:"url"`
+	ProtocolVersion int          `json:"protocolVersion"`
+	AuthMode        string       `json:"authMode"`
+	Capabilities    Capabilities `json:"capabilities"`
+}
+
+// capabilitiesDoc risposta XML della negoziazione
+type capabilitiesDoc struct {
+	XMLName  xml.Name `xml:"capabilities"`
+	Versions []string `xml:"version"`
+	Auth     []string `xml:"auth"`
+}
+
+// negotiateRetry dopo una negoziazione fallita (rete) si riprova solo
+// trascorso questo intervallo
+const negotiateRetry = time.Minute
+
+// capsKey chiave della cache: le capacità dipendono anche dalla versione
+// richiesta (un servlet storico accetta solo quella configurata)
+type capsKey struct {
+	url     string
+	version int
+}
+
+// capsEntry negoziazione in corso o completata: chiamate concorrenti per
+// la stessa chiave attendono done invece di ripetere la richiesta
+type capsEntry struct {
+	done    chan struct{}
+	caps    Capabilities
+	expires time.Time // zero = valida fino al reset
+}
+
+var (
+	capsMu    sync.Mutex
+	capsCache = make(map[capsKey]*capsEntry)
+)
+
+// servletURL URL del servlet senza parametri
+func servletURL(cfg *config.Config) string {
+	return strings.TrimSuffix(cfg.ServerURL, "/") + cfg.ServletPath
+}
+
+// Servlet ritorna i parametri effettivi (negozia se authMode è auto)
+func Servlet(cfg *config.Config) ServletInfo {
+	info := ServletInfo{
+		URL:             servletURL(cfg),
+		ProtocolVersion: cfg.ProtocolVersion,
+		AuthMode:        cfg.AuthMode,
+	}
+	if cfg.AuthMode != config.AuthAuto {
+		info.Capabilities = Capabilities{
+			Versions:  []int{cfg.ProtocolVersion},
+			AuthModes: []string{cfg.AuthMode},
+		}
+		return info
+	}
+
+	caps := negotiate(cfg)
+	info.Capabilities = caps
+	info.AuthMode = preferredAuth(caps.AuthModes)
+	info.ProtocolVersion = supportedVersion(caps.Versions, cfg.ProtocolVersion)
+	return info
+}
+
+// ResetCapabilities dimentica le capacità rilevate (nuova negoziazione)
+func ResetCapabilities() {
+	capsMu.Lock()
+	capsCache = make(map[capsKey]*capsEntry)
+	capsMu.Unlock()
+}
+
+// forgetCapabilitiesOnAuthError rinegozia alla prossima chiamata se il
+// servlet rifiuta le credenziali (modalità cambiata lato server)
+func forgetCapabilitiesOnAuthError(cfg *config.Config, status int) {
+	if cfg.AuthMode != config.AuthAuto {
+		return
+	}
+	if status != http.StatusUnauthorized && status != http.StatusForbidden {
+		return
+	}
+	capsMu.Lock()
+	delete(capsCache, capsKey{url: servletURL(cfg), version: cfg.ProtocolVersion})
+	capsMu.Unlock()
+}
+
+// negotiate ritorna le capacità del servlet (in cache per URL e versione).
+// La richiesta avviene senza lock globale: solo le chiamate per la stessa
+// chiave attendono la negoziazione in corso.
+func negotiate(cfg *config.Config) Capabilities {
+	key := capsKey{url: servletURL(cfg), version: cfg.ProtocolVersion}
+
+	capsMu.Lock()
+	if e, ok := capsCache[key]; ok {
+		capsMu.Unlock()
+		<-e.done
+		if e.expires.IsZero() || time.Now().Before(e.expires) {
+			return e.caps
+		}
+		capsMu.Lock()
+		// Scaduta: la prima chiamata la sostituisce, le altre attendono quella
+		if current, ok := capsCache[key]; ok && current != e {
+			capsMu.Unlock()
+			<-current.done
+			return current.caps
+		}
+	}
+	e := &capsEntry{done: make(chan struct{})}
+	capsCache[key] = e
+	capsMu.Unlock()
+
+	caps, ok := probeCapabilities(cfg, key.url)
+	e.caps = caps
+	if !ok {
+		e.expires = time.Now().Add(negotiateRetry)
+	}
+	close(e.done)
+	return caps
+}
+
+// probeCapabilities esegue la negoziazione; false se il servlet non era
+// raggiungibile (risultato da non conservare a lungo)
+func probeCapabilities(cfg *config.Config, servlet string) (Capabilities, bool) {
+	legacy := Capabilities{
+		Versions:  []int{cfg.ProtocolVersion},
+		AuthModes: []string{config.AuthNone},
+		CheckedAt: time.Now(),
+	}
+
+	params := url.Values{}
+	params.Add("capabilities", "1")
+	params.Add("version", strconv.Itoa(cfg.ProtocolVersion))
+	req, err := http.NewRequest(http.MethodGet, servlet+"?"+params.Encode(), nil)
+	if err != nil {
+		return legacy, false
+	}
+
+	resp, err := httpclient.Do(req)
+	if err != nil {
+		log.Printf("Servlet capabilities unavailable, retry in %s: %v", negotiateRetry, err)
+		return legacy, false
+	}
+	defer resp.Body.Close()
+
+	caps := legacy
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		if mode := authFromChallenge(resp.Header.Get("WWW-Authenticate")); mode != "" {
+			caps.AuthModes = []string{mode}
+			caps.Negotiated = true
+		}
+	case resp.StatusCode == http.StatusOK:
+		// Solo l'inizio: un servlet storico risponde con la programmazione
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if parsed, ok := parseCapabilities(body); ok {
+			caps = parsed
+			caps.CheckedAt = legacy.CheckedAt
+		}
+	}
+
+	if caps.Negotiated {
+		log.Printf("Servlet capabilities: versions %v, auth %v", caps.Versions, caps.AuthModes)
+	} else {
+		log.Printf("Servlet capabilities not advertised: legacy protocol %d, no authentication", cfg.ProtocolVersion)
+	}
+	return caps, true
+}
+
+// parseCapabilities interpreta la risposta di negoziazione
+func parseCapabilities(body []byte) (Capabilities, bool) {
+	var doc capabilitiesDoc
+	d := xml.NewDecoder(bytes.NewReader(body))
+	d.CharsetReader = charsetReader
+	if err := d.Decode(&doc); err != nil {
+		return Capabilities{}, false
+	}
+
+	caps := Capabilities{Negotiated: true, Versions: []int{}, AuthModes: []string{}}
+	for _, v := range doc.Versions {
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && n > 0 {
+			caps.Versions = append(caps.Versions, n)
+		}
+	}
+	sort.Ints(caps.Versions)
+	for _, a := range doc.Auth {
+		mode := strings.ToLower(strings.TrimSpace(a))
+		switch mode {
+		case config.AuthNone, config.AuthQuery, config.AuthBasic, config.AuthToken:
+			caps.AuthModes = append(caps.AuthModes, mode)
+		}
+	}
+	if len(caps.AuthModes) == 0 {
+		caps.AuthModes = []string{config.AuthNone}
+	}
+	return caps, true
+}
+
+// authFromChallenge modalità dallo schema di WWW-Authenticate
+func authFromChallenge(challenge string) string {
+	scheme, _, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	switch strings.ToLower(scheme) {
+	case "basic":
+		return config.AuthBasic
+	case "bearer":
+		return config.AuthToken
+	}
+	return ""
+}
+
+// preferredAuth sceglie la modalità più sicura tra quelle supportate
+func preferredAuth(modes []string) string {
+	for _, preferred := range []string{config.AuthToken, config.AuthBasic, config.AuthQuery} {
+		for _, m := range modes {
+			if m == preferred {
+				return m
+			}
+		}
+	}
+	return config.AuthNone
+}
+
+// supportedVersion versione configurata se supportata, altrimenti la più
+// alta supportata dal servlet che non la superi
+func supportedVersion(versions []int, configured int) int {
+	best := 0
+	for _, v := range versions {
+		if v == configured {
+			return v
+		}
+		if v < configured && v > best {
+			best = v
+		}
+	}
+	if best == 0 {
+		return configured
+	}
+	return best
+}
+
+// newServletRequest prepara una GET al servlet con versione e autenticazione
+func newServletRequest(cfg *config.Config, params url.Values) (*http.Request, error) {
+	info := Servlet(cfg)
+
+	params.Set("version", strconv.Itoa(info.ProtocolVersion))
+	if info.AuthMode == config.AuthQuery {
+		params.Set("username", cfg.Username)
+		params.Set("password", cfg.Password)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, info.URL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, apperr.Wrap(err, CodeInvalidServerURL, "invalid server URL").
+			WithDetail("serverUrl", cfg.ServerURL)
+	}
+
+	switch info.AuthMode {
+	case config.AuthBasic:
+		req.SetBasicAuth(cfg.Username, cfg.Password)
+	case config.AuthToken:
+		req.Header.Set("Authorization", "Bearer "+cfg.Password)
+	}
+	return req, nil
+}
@@ -0,0 +1,168 @@
+package xml
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"spotlive-server/internal/config"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// servletConfig configurazione di test verso srv
+func servletConfig(srv *httptest.Server, mode string) *config.Config {
+	cfg := config.GetDefault()
+	cfg.ServerURL = srv.URL
+	cfg.ServletPath = "/staging/XmlServlet"
+	cfg.Username = "user"
+	cfg.Password = "secret"
+	cfg.AuthMode = mode
+	return cfg
+}
+
+func TestServletNegotiatesCapabilities(t *testing.T) {
+	defer ResetCapabilities()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/staging/XmlServlet" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("capabilities") == "1" {
+			w.Write([]byte(`<capabilities><version>600</version><version>700</version><auth>query</auth><auth>basic</auth></capabilities>`))
+			return
+		}
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer srv.Close()
+
+	cfg := servletConfig(srv, config.AuthAuto)
+	cfg.ProtocolVersion = 650
+
+	info := Servlet(cfg)
+	if !info.Capabilities.Negotiated || info.AuthMode != config.AuthBasic || info.ProtocolVersion != 600 {
+		t.Fatalf("servlet = %+v; want negotiated basic, version 600", info)
+	}
+
+	req, err := newServletRequest(cfg, url.Values{"idSchermo": {"567"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user, pass, ok := req.BasicAuth(); !ok || user != "user" || pass != "secret" {
+		t.Errorf("basic auth = %q %q %v", user, pass, ok)
+	}
+	if q := req.URL.Query(); q.Get("version") != "600" || q.Get("password") != "" {
+		t.Errorf("query = %v", q)
+	}
+}
+
+func TestServletLegacyAndChallenge(t *testing.T) {
+	defer ResetCapabilities()
+
+	legacy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<it.zerounorabbit.spotlivescreen.SchermoXml/>`))
+	}))
+	defer legacy.Close()
+
+	info := Servlet(servletConfig(legacy, config.AuthAuto))
+	if info.Capabilities.Negotiated || info.AuthMode != config.AuthNone || info.ProtocolVersion != config.DefaultProtocolVersion {
+		t.Errorf("legacy servlet = %+v; want none, %d", info, config.DefaultProtocolVersion)
+	}
+
+	bearer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="spotlive"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer bearer.Close()
+
+	cfg := servletConfig(bearer, config.AuthAuto)
+	req, err := newServletRequest(cfg, url.Values{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("Authorization = %q; want bearer token", got)
+	}
+}
+
+func TestServletExplicitQueryAuth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("explicit auth mode must not negotiate")
+	}))
+	defer srv.Close()
+
+	cfg := servletConfig(srv, config.AuthQuery)
+	cfg.ProtocolVersion = 700
+	req, err := newServletRequest(cfg, url.Values{"idSchermo": {"567"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := req.URL.Query()
+	if req.URL.Path != "/staging/XmlServlet" || q.Get("version") != "700" || q.Get("username") != "user" || q.Get("password") != "secret" {
+		t.Errorf("request = %s", req.URL)
+	}
+}
+
+func TestServletCapabilitiesFollowProtocolVersion(t *testing.T) {
+	defer ResetCapabilities()
+
+	legacy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<it.zerounorabbit.spotlivescreen.SchermoXml/>`))
+	}))
+	defer legacy.Close()
+
+	cfg := servletConfig(legacy, config.AuthAuto)
+	if v := Servlet(cfg).ProtocolVersion; v != config.DefaultProtocolVersion {
+		t.Fatalf("version = %d; want %d", v, config.DefaultProtocolVersion)
+	}
+	cfg.ProtocolVersion = 700
+	if v := Servlet(cfg).ProtocolVersion; v != 700 {
+		t.Errorf("version after change = %d; want 700", v)
+	}
+}
+
+func TestServletNegotiationSharedAndFailureCached(t *testing.T) {
+	defer ResetCapabilities()
+
+	var hits int32
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(`<capabilities><version>600</version><auth>basic</auth></capabilities>`))
+	}))
+	defer slow.Close()
+
+	cfg := servletConfig(slow, config.AuthAuto)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if mode := Servlet(cfg).AuthMode; mode != config.AuthBasic {
+				t.Errorf("auth = %q; want basic", mode)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Errorf("concurrent negotiations = %d; want 1", n)
+	}
+
+	// Servlet irraggiungibile: un solo tentativo fino a negotiateRetry
+	var drops int32
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&drops, 1)
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer broken.Close()
+
+	cfg = servletConfig(broken, config.AuthAuto)
+	Servlet(cfg)
+	Servlet(cfg)
+	if n := atomic.LoadInt32(&drops); n != 1 {
+		t.Errorf("negotiation attempts after failure = %d; want 1", n)
+	}
+}
@@ -0,0 +1,138 @@
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"os"
+	"spotlive-server/internal/apperr"
+)
+
+// Decodifica in streaming: la risposta del servlet non viene mai tenuta per
+// intero in memoria. Il body passa da un lettore limitato (maxScheduleBytes)
+// a un file temporaneo; senza attributi reference il documento è decodificato
+// token per token direttamente dal file, altrimenti viene costruito l'albero
+// per espandere i riferimenti (limitato da maxExpandedNodes/maxExpandedBytes).
+
+// referenceMarker attributo XStream che richiede l'albero
+var referenceMarker = []byte("reference")
+
+// limitedReader legge al massimo limit byte: oltre, errore SCHEDULE_TOO_LARGE
+// (io.LimitReader troncherebbe in silenzio il documento)
+type limitedReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.read >= l.limit {
+		// Limite raggiunto: errore solo se il body continua
+		var probe [1]byte
+		n, err := l.r.Read(probe[:])
+		if n > 0 {
+			return 0, tooLargeError(l.limit)
+		}
+		return 0, err
+	}
+	if rest := l.limit - l.read; int64(len(p)) > rest {
+		p = p[:rest]
+	}
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	return n, err
+}
+
+// spoolBody copia il body (al massimo limit byte) in un file temporaneo in
+// dir e lo riavvolge. Il chiamante chiude e rimuove il file.
+func spoolBody(body io.Reader, limit int64, dir string) (*os.File, error) {
+	f, err := os.CreateTemp(dir, "schedule-*.xml")
+	if err != nil && dir != "" {
+		// Cache non ancora creata: directory temporanea di sistema
+		f, err = os.CreateTemp("", "schedule-*.xml")
+	}
+	if err != nil {
+		return nil, apperr.Wrap(err, CodeScheduleRead, "schedule spool failed")
+	}
+	discard := func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+
+	if _, err := io.Copy(f, &limitedReader{r: body, limit: limit}); err != nil {
+		discard()
+		if apperr.From(err) != nil {
+			return nil, err
+		}
+		return nil, apperr.Wrap(err, CodeScheduleRead, "read body failed").AsRetryable()
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		discard()
+		return nil, apperr.Wrap(err, CodeScheduleRead, "schedule spool failed")
+	}
+	return f, nil
+}
+
+// containsReference cerca referenceMarker leggendo r a blocchi (anche a
+// cavallo di due blocchi). Un falso positivo costa solo l'albero.
+func containsReference(r io.Reader) (bool, error) {
+	buf := make([]byte, 32<<10)
+	keep := len(referenceMarker) - 1
+	n := 0
+	for {
+		m, err := r.Read(buf[n:])
+		n += m
+		if bytes.Contains(buf[:n], referenceMarker) {
+			return true, nil
+		}
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if n > keep {
+			n = copy(buf, buf[n-keep:n])
+		}
+	}
+}
+
+// treeFrame elemento in corso di emissione
+type treeFrame struct {
+	n    *node
+	next int
+}
+
+// treeReader emette i token dell'albero in ordine di documento
+// (xml.TokenReader per xml.NewTokenDecoder)
+type treeReader struct {
+	stack []treeFrame
+	line  int // riga dell'ultimo elemento emesso (per i warning)
+}
+
+func newTreeReader(doc *node) *treeReader {
+	return &treeReader{stack: []treeFrame{{n: doc}}}
+}
+
+func (t *treeReader) Token() (xml.Token, error) {
+	for len(t.stack) > 0 {
+		top := &t.stack[len(t.stack)-1]
+		if top.next < len(top.n.items) {
+			it := top.n.items[top.next]
+			top.next++
+			if c, ok := it.(*node); ok {
+				t.line = c.line
+				t.stack = append(t.stack, treeFrame{n: c})
+				return c.start, nil
+			}
+			return it, nil
+		}
+
+		// Fine elemento (la radice virtuale non ha tag di chiusura)
+		t.stack = t.stack[:len(t.stack)-1]
+		if len(t.stack) > 0 {
+			return top.n.start.End(), nil
+		}
+	}
+	return nil, io.EOF
+}
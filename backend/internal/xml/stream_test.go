@@ -0,0 +1,112 @@
+package xml
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"spotlive-server/internal/apperr"
+	"spotlive-server/internal/config"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFetchScheduleTooLarge(t *testing.T) {
+	body := `<it.zerounorabbit.spotlivescreen.SchermoXml><schermo><nome>` +
+		strings.Repeat("x", 100<<10) +
+		`</nome></schermo></it.zerounorabbit.spotlivescreen.SchermoXml>`
+
+	declared := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if declared {
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		} else {
+			// Chunked: dimensione nota solo leggendo
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	config.SetDataDir(t.TempDir())
+	err := config.SetOverlay(nil, map[string]string{
+		"serverUrl":        srv.URL,
+		"authMode":         config.AuthNone,
+		"username":         "user",
+		"password":         "secret",
+		"idMonitor":        "567",
+		"maxScheduleBytes": strconv.Itoa(config.MinScheduleBytesLimit),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer config.SetOverlay(nil, nil)
+	if _, err := config.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, declared = range []bool{false, true} {
+		_, err := FetchSchedule()
+		if code := apperr.CodeOf(err); code != CodeScheduleTooLarge {
+			t.Errorf("declared length %v: error %v; want %s", declared, err, CodeScheduleTooLarge)
+		}
+	}
+}
+
+func TestSpoolBodyAtBoundary(t *testing.T) {
+	data, err := os.ReadFile("testdata/multi_zone.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := spoolBody(bytes.NewReader(data), int64(len(data)), t.TempDir())
+	if err != nil {
+		t.Fatalf("body of exactly the limit rejected: %v", err)
+	}
+	defer f.Close()
+	if _, err := ParseReader(f); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if _, err := spoolBody(bytes.NewReader(data), int64(len(data)-1), dir); apperr.CodeOf(err) != CodeScheduleTooLarge {
+		t.Fatalf("error = %v; want %s", err, CodeScheduleTooLarge)
+	}
+	if left, _ := os.ReadDir(dir); len(left) != 0 {
+		t.Errorf("spool file left behind: %v", left)
+	}
+}
+
+func TestLargeScheduleWithoutReferencesSkipsTree(t *testing.T) {
+	// Più elementi di maxExpandedNodes: accettati solo se decodificati
+	// direttamente dal flusso, senza costruire l'albero
+	var b strings.Builder
+	b.WriteString(`<it.zerounorabbit.spotlivescreen.SchermoXml><schermo><nome>big</nome></schermo>`)
+	b.WriteString(strings.Repeat("<x/>", maxExpandedNodes+10))
+	b.WriteString(`</it.zerounorabbit.spotlivescreen.SchermoXml>`)
+	data := b.String()
+
+	s, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("reference-free schedule: %v", err)
+	}
+	if s.Schermo.Nome != "big" {
+		t.Errorf("nome = %q", s.Schermo.Nome)
+	}
+
+	// Con un riferimento lo stesso documento passa dall'albero
+	withRef := strings.Replace(data, "<x/>", `<x reference="../schermo"/>`, 1)
+	if _, err := Parse([]byte(withRef)); err == nil || !strings.Contains(err.Error(), "elements") {
+		t.Fatalf("error = %v; want element limit", err)
+	}
+}
+
+func TestContainsReferenceAcrossChunks(t *testing.T) {
+	// Marcatore a cavallo del blocco di lettura da 32 KiB
+	data := strings.Repeat(" ", (32<<10)-4) + `<m reference="1"/>`
+	found, err := containsReference(strings.NewReader(data))
+	if err != nil || !found {
+		t.Fatalf("found = %v, err = %v", found, err)
+	}
+}
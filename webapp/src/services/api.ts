@@ -1,77 +1,106 @@
-import type {
-  Config,
-  ConfigResponse,
-  ScheduleResponse,
-  DownloadResponse,
-  StatusResponse,
-  TestConnectionResponse
-} from '../types';
-
-class APIService {
-  private baseUrl = '/api';
-
-  async getConfig(): Promise<ConfigResponse> {
-    const response = await fetch(`${this.baseUrl}/config`);
-    if (!response.ok) throw new Error('Failed to fetch config');
-    return response.json();
-  }
-
-  async saveConfig(config: Partial<Config>): Promise<{ success: boolean }> {
-    const response = await fetch(`${this.baseUrl}/config`, {
-      method: 'POST',
-      headers: { 'Content-Type': 'application/json' },
-      body: JSON.stringify(config)
-    });
-    if (!response.ok) throw new Error('Failed to save config');
-    return response.json();
-  }
-
-  async testConnection(): Promise<TestConnectionResponse> {
-    const response = await fetch(`${this.baseUrl}/config/test`, {
-      method: 'POST'
-    });
-    if (!response.ok) throw new Error('Connection test failed');
-    return response.json();
-  }
-
-  async getSchedule(): Promise<ScheduleResponse> {
-    const response = await fetch(`${this.baseUrl}/schedule`);
-    if (!response.ok) throw new Error('Failed to fetch schedule');
-    return response.json();
-  }
-
-  async downloadAllMedia(): Promise<DownloadResponse> {
-    const response = await fetch(`${this.baseUrl}/media/download-all`, {
-      method: 'POST'
-    });
-    if (!response.ok) throw new Error('Failed to download media');
-    return response.json();
-  }
-
-  async sendHeartbeat(): Promise<{ success: boolean }> {
-    const response = await fetch(`${this.baseUrl}/heartbeat`, {
-      method: 'POST'
-    });
-    if (!response.ok) throw new Error('Failed to send heartbeat');
-    return response.json();
-  }
-
-  async getStatus(): Promise<StatusResponse> {
-    const response = await fetch(`${this.baseUrl}/status`);
-    if (!response.ok) throw new Error('Failed to fetch status');
-    return response.json();
-  }
-
-  getMediaUrl(filename: string): string {
-    // Remove "upload/" prefix if present
-    const cleanFilename = filename.replace(/^upload\//, '');
-    return `${this.baseUrl}/media/upload/${encodeURIComponent(cleanFilename)}`;
-  }
-
-  getCachedMediaUrl(filename: string): string {
-    const cleanFilename = filename.replace(/^upload\//, '');
-    return `${this.baseUrl}/cache/${encodeURIComponent(cleanFilename)}`;
-  }
-}
-
-export const api = new APIService();
+import type {
+  ApiErrorBody,
+  Config,
+  ConfigResponse,
+  ScheduleResponse,
+  DownloadResponse,
+  StatusResponse,
+  TestConnectionResponse
+} from '../types';
+
+export class APIError extends Error {
+  code: string;
+  details?: Record<string, any>;
+  retryable: boolean;
+
+  constructor(body: ApiErrorBody) {
+    super(body.message);
+    this.code = body.code;
+    this.details = body.details;
+    this.retryable = body.retryable;
+  }
+}
+
+class APIService {
+  private baseUrl = '/api';
+
+  // Converte risposta non-ok in APIError (envelope {code, message, details, retryable})
+  private async check(response: Response, fallback: string): Promise<void> {
+    if (response.ok) return;
+    let body: any;
+    try {
+      body = await response.json();
+    } catch {
+      throw new Error(fallback);
+    }
+    if (body && body.error && body.error.code) {
+      throw new APIError(body.error);
+    }
+    throw new Error(fallback);
+  }
+
+  async getConfig(): Promise<ConfigResponse> {
+    const response = await fetch(`${this.baseUrl}/config`);
+    await this.check(response, 'Failed to fetch config');
+    return response.json();
+  }
+
+  async saveConfig(config: Partial<Config>): Promise<{ success: boolean }> {
+    const response = await fetch(`${this.baseUrl}/config`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(config)
+    });
+    await this.check(response, 'Failed to save config');
+    return response.json();
+  }
+
+  async testConnection(): Promise<TestConnectionResponse> {
+    const response = await fetch(`${this.baseUrl}/config/test`, {
+      method: 'POST'
+    });
+    await this.check(response, 'Connection test failed');
+    return response.json();
+  }
+
+  async getSchedule(): Promise<ScheduleResponse> {
+    const response = await fetch(`${this.baseUrl}/schedule`);
+    await this.check(response, 'Failed to fetch schedule');
+    return response.json();
+  }
+
+  async downloadAllMedia(): Promise<DownloadResponse> {
+    const response = await fetch(`${this.baseUrl}/media/download-all`, {
+      method: 'POST'
+    });
+    await this.check(response, 'Failed to download media');
+    return response.json();
+  }
+
+  async sendHeartbeat(): Promise<{ success: boolean }> {
+    const response = await fetch(`${this.baseUrl}/heartbeat`, {
+      method: 'POST'
+    });
+    await this.check(response, 'Failed to send heartbeat');
+    return response.json();
+  }
+
+  async getStatus(): Promise<StatusResponse> {
+    const response = await fetch(`${this.baseUrl}/status`);
+    await this.check(response, 'Failed to fetch status');
+    return response.json();
+  }
+
+  getMediaUrl(filename: string): string {
+    // Remove "upload/" prefix if present
+    const cleanFilename = filename.replace(/^upload\//, '');
+    return `${this.baseUrl}/media/upload/${encodeURIComponent(cleanFilename)}`;
+  }
+
+  getCachedMediaUrl(filename: string): string {
+    const cleanFilename = filename.replace(/^upload\//, '');
+    return `${this.baseUrl}/cache/${encodeURIComponent(cleanFilename)}`;
+  }
+}
+
+export const api = new APIService();
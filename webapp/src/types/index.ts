@@ -1,132 +1,184 @@
-// Configuration
-export interface Config {
-  serverUrl: string;
-  username: string;
-  password: string;
-  idMonitor: string;
-  userSchermo?: string;
-  ftpServer?: string;
-  ftpPort?: number;
-  ftpUsername?: string;
-  ftpPassword?: string;
-}
-
-export interface ConfigResponse {
-  configured: boolean;
-  config?: Config;
-}
-
-// Schedule structures
-export interface Schermo {
-  id: number;
-  nome: string;
-  indirizzo: string;
-  larghezza: number;
-  altezza: number;
-  attivo: boolean;
-  finestre: Finestra[];
-  orari: Orario[];
-  categoriaMerceologica: Categoria;
-  lun: boolean;
-  mar: boolean;
-  mer: boolean;
-  gio: boolean;
-  ven: boolean;
-  sab: boolean;
-  dom: boolean;
-  elenco: boolean;
-  oraDownload01: string;
-  oraDownload02: string;
-  oraDownload03: string;
-  oraDownload04: string;
-  oraDownload05: string;
-  oraDownload06: string;
-  oraDownload07: string;
-  oraDownload08: string;
-  oraRestart: string;
-}
-
-export interface Finestra {
-  id: number;
-  nome: string;
-  altezza: number;
-  larghezza: number;
-  alto: number;
-  destra: number;
-  attiva: boolean;
-  spot: boolean;
-  imgNoInternet: string;
-  audio: boolean;
-}
-
-export interface Orario {
-  id: number;
-  oraInizio: string;
-  oraFine: string;
-  crediti: number;
-}
-
-export interface MediaFinestra {
-  id: number;
-  tipo: MediaType;
-  ordine: number;
-  media: Media;
-}
-
-export interface Media {
-  id: number;
-  nome: string;
-  attivo: boolean;
-  tipo: MediaType;
-  video?: string;
-  immagine?: string;
-  audio?: string;
-  miniatura?: string;
-  pubblico: boolean;
-  tempo: number;
-  numeroNotizie: number;
-  approvato: boolean;
-  crediti: number;
-  categoria: Categoria;
-}
-
-export interface Categoria {
-  id: number;
-  nome: string;
-}
-
-export interface SchermoXml {
-  schermo: Schermo;
-  mediaFinestre: MediaFinestra[];
-  programmazioni: any;
-  media: Media[];
-}
-
-export type MediaType = 'VIDEO' | 'IMAGE' | 'RSS' | 'WEB' | 'YOUTUBE';
-
-// API Responses
-export interface ScheduleResponse {
-  success: boolean;
-  schedule?: SchermoXml;
-  error?: string;
-}
-
-export interface DownloadResponse {
-  success: boolean;
-  message?: string;
-  error?: string;
-}
-
-export interface StatusResponse {
-  configured: boolean;
-  idMonitor: string;
-  mediaCount: number;
-  version: string;
-}
-
-export interface TestConnectionResponse {
-  success: boolean;
-  message?: string;
-  schermo?: string;
-  error?: string;
-}
+// Configuration
+export interface Config {
+  serverUrl: string;
+  username: string;
+  password: string;
+  idMonitor: string;
+  userSchermo?: string;
+  ftpServer?: string;
+  ftpPort?: number;
+  ftpUsername?: string;
+  ftpPassword?: string;
+}
+
+export interface ConfigResponse {
+  configured: boolean;
+  config?: Config;
+}
+
+// Schedule structures
+export interface Schermo {
+  id: number;
+  nome: string;
+  indirizzo: string;
+  larghezza: number;
+  altezza: number;
+  attivo: boolean;
+  finestre: Finestra[];
+  orari: Orario[];
+  categoriaMerceologica: Categoria;
+  lun: boolean;
+  mar: boolean;
+  mer: boolean;
+  gio: boolean;
+  ven: boolean;
+  sab: boolean;
+  dom: boolean;
+  elenco: boolean;
+  oraDownload01: string;
+  oraDownload02: string;
+  oraDownload03: string;
+  oraDownload04: string;
+  oraDownload05: string;
+  oraDownload06: string;
+  oraDownload07: string;
+  oraDownload08: string;
+  oraRestart: string;
+}
+
+export interface Finestra {
+  id: number;
+  nome: string;
+  altezza: number;
+  larghezza: number;
+  alto: number;
+  destra: number;
+  attiva: boolean;
+  spot: boolean;
+  imgNoInternet: string;
+  audio: boolean;
+}
+
+export interface Orario {
+  id: number;
+  oraInizio: string;
+  oraFine: string;
+  crediti: number;
+}
+
+export interface MediaFinestra {
+  id: number;
+  tipo: MediaType;
+  ordine: number;
+  media: Media;
+}
+
+export interface Media {
+  id: number;
+  nome: string;
+  attivo: boolean;
+  tipo: MediaType;
+  video?: string;
+  immagine?: string;
+  audio?: string;
+  miniatura?: string;
+  pubblico: boolean;
+  tempo: number;
+  numeroNotizie: number;
+  approvato: boolean;
+  crediti: number;
+  categoria: Categoria;
+}
+
+export interface Categoria {
+  id: number;
+  nome: string;
+}
+
+// Campagna: media in onda solo tra dataInizio e dataFine (RFC 3339, null = nessun limite)
+export interface Programmazione {
+  id: number;
+  nome: string;
+  dataInizio: string | null;
+  dataFine: string | null;
+  fine: string | null; // fine esclusiva già calcolata dal backend
+  attiva: boolean | null;
+  finestre: Finestra[];
+  media: Media[];
+}
+
+export interface SchermoXml {
+  schermo: Schermo;
+  mediaFinestre: MediaFinestra[];
+  programmazioni: Programmazione[] | null;
+  media: Media[];
+  warnings?: ScheduleWarning[];
+}
+
+// Campo della programmazione non interpretabile (lasciato a zero dal backend)
+export interface ScheduleWarning {
+  field: string;
+  value: string;
+  line: number;
+  message: string;
+}
+
+export type MediaType = 'VIDEO' | 'IMAGE' | 'RSS' | 'WEB' | 'YOUTUBE';
+
+// API Responses
+export interface ApiErrorBody {
+  code: string;
+  message: string;
+  details?: Record<string, any>;
+  retryable: boolean;
+}
+
+export interface ErrorResponse {
+  success: false;
+  error: ApiErrorBody;
+}
+
+export interface ScheduleResponse {
+  success: boolean;
+  schedule?: SchermoXml;
+  playlist?: MediaFinestra[];
+  lint?: LintReport;
+  error?: ApiErrorBody;
+}
+
+// Controlli semantici sulla programmazione (codici stabili, vedi backend/internal/schedule/lint.go)
+export interface LintIssue {
+  code: string;
+  severity: 'error' | 'warning';
+  message: string;
+  zones?: number[];
+  item?: number;
+  window?: number;
+  campaign?: number;
+}
+
+export interface LintReport {
+  errors: number;
+  warnings: number;
+  issues: LintIssue[];
+}
+
+export interface DownloadResponse {
+  success: boolean;
+  message?: string;
+  error?: ApiErrorBody;
+}
+
+export interface StatusResponse {
+  configured: boolean;
+  idMonitor: string;
+  mediaCount: number;
+  version: string;
+}
+
+export interface TestConnectionResponse {
+  success: boolean;
+  message?: string;
+  schermo?: string;
+  error?: ApiErrorBody;
+}